@@ -0,0 +1,17 @@
+//go:build brotli
+// +build brotli
+
+package main
+
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Built with -tags brotli so the core tool doesn't need the dependency
+func init() {
+	contentDecoders["br"] = func(r io.Reader) (io.Reader, error) {
+		return brotli.NewReader(r), nil
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// Values shorter than this reflect everywhere by coincidence, -by-value
+// skips them
+const minByValueLen = 4
+
+// Where a value was sent or reflected in the capture
+type ValueLocation struct {
+	File  string `json:"file,omitempty"` // When reading files instead of stdin
+	Entry int    `json:"entry"`
+	URL   string `json:"url"`
+	Key   string `json:"key,omitempty"` // Of inputs
+}
+
+// One distinct value with every request that sent it and every response it
+// reflects in, in any entry of the capture
+type ValueReport struct {
+	Value       string          `json:"value"`
+	Inputs      []ValueLocation `json:"inputs"`
+	Reflections []ValueLocation `json:"reflections"`
+}
+
+// Reads all of the files, or stdin without any, and writes a ValueReport per value that reflects anywhere.
+// Unlike the default per entry results each value is searched for in every
+// response, to follow something like a username through a session.
+func writeByValue(paths []string, w io.Writer) error {
+	entries := make(chan indexedEntry)
+	errChan := make(chan error, 1)
+	go func() {
+		defer close(entries)
+		errChan <- readFiles(context.Background(), paths, entries)
+	}()
+
+	type body struct {
+		ValueLocation
+		text    string
+		decoded string // See decodedBodyText
+	}
+	bodies := []body{}
+	reports := map[string]*ValueReport{}
+	for e := range entries {
+		if reason, err := filterEntry(e.entry); reason != "" || err != nil {
+			continue
+		}
+		text, err := decodeResponse(&e.entry.Response)
+		if err != nil {
+			slog.Warn("Skipping entry", "index", e.i, "url", e.entry.Request.URL, "err", err)
+			continue
+		}
+		bodies = append(bodies, body{ValueLocation{File: e.file, Entry: e.i, URL: e.entry.Request.URL}, text, decodedBodyText(text)})
+		for keyValue := range searchEntry(context.Background(), e.entry) {
+			if len(keyValue.Value) < minByValueLen || (paramValueRegexp != nil && !paramValueRegexp.MatchString(keyValue.Value)) {
+				continue
+			}
+			report, ok := reports[keyValue.Value]
+			if !ok {
+				report = &ValueReport{Value: keyValue.Value}
+				reports[keyValue.Value] = report
+			}
+			report.Inputs = append(report.Inputs, ValueLocation{
+				File:  e.file,
+				Entry: e.i,
+				URL:   e.entry.Request.URL,
+				Key:   keyValue.Path(),
+			})
+		}
+	}
+	if err := <-errChan; err != nil {
+		return err
+	}
+
+	values := make([]string, 0, len(reports))
+	for value := range reports {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	output := []*ValueReport{}
+	for _, value := range values {
+		report := reports[value]
+		for _, body := range bodies {
+			if strings.Contains(body.text, value) || (body.decoded != "" && strings.Contains(body.decoded, value)) {
+				report.Reflections = append(report.Reflections, body.ValueLocation)
+			}
+		}
+		if 0 < len(report.Reflections) {
+			output = append(output, report)
+		}
+	}
+	return encodeJSON(w, output)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+var canaryFlag = flag.String("canary", "", "Instead of results per entry, output every place this literal marker is in a response of the capture, whatever param it came from. Every response is searched, -content-types doesn't apply")
+var canaryHeadersFlag = flag.Bool("canary-headers", false, "With -canary, also search response headers")
+
+// Where the -canary marker is in a response
+type CanaryHit struct {
+	File    string `json:"file,omitempty"` // When reading files instead of stdin
+	Entry   int    `json:"entry"`
+	Method  string `json:"method"`
+	URL     string `json:"url"`
+	Header  string `json:"header,omitempty"` // Name of the header it's in, else it's in the body
+	Match   Match  `json:"match"`
+	Context string `json:"context,omitempty"` // Like KeyValue.Context
+
+	DecodedBody bool `json:"decodedBody,omitempty"` // Like KeyValue.DecodedBody
+}
+
+// Reads all of the files, or stdin without any, and writes every hit of the
+// -canary marker. Params aren't searched at all, only the responses.
+func writeCanary(paths []string, w io.Writer) error {
+	entries := make(chan indexedEntry)
+	errChan := make(chan error, 1)
+	go func() {
+		defer close(entries)
+		errChan <- readFiles(context.Background(), paths, entries)
+	}()
+
+	canary := *canaryFlag
+	hits := []*CanaryHit{}
+	for e := range entries {
+		if reason, err := filterEntryWith(e.entry, false); reason != "" || err != nil {
+			continue
+		}
+		hit := func(header, text string, offset int) *CanaryHit {
+			return &CanaryHit{
+				File:   e.file,
+				Entry:  e.i,
+				Method: e.entry.Request.Method,
+				URL:    e.entry.Request.URL,
+				Header: header,
+				Match:  newMatch(text, offset, len(canary)),
+			}
+		}
+		if *canaryHeadersFlag {
+			for _, h := range e.entry.Response.Headers {
+				for _, offset := range indexAll(h.Value, canary) {
+					hits = append(hits, hit(h.Name, h.Value, offset))
+				}
+			}
+		}
+		text, err := decodeResponse(&e.entry.Response)
+		if err != nil {
+			slog.Warn("Skipping entry", "index", e.i, "url", e.entry.Request.URL, "err", err)
+			continue
+		}
+		mimeType := responseMimeType(&e.entry.Response)
+		bodyHits := func(text string, decoded bool) {
+			for _, offset := range indexAll(text, canary) {
+				h := hit("", text, offset)
+				keyValue := &KeyValue{Value: canary}
+				if strings.Contains(mimeType, "html") {
+					describeContext(text, offset, keyValue)
+				} else if mimeType == "image/svg+xml" {
+					describeSVGContext(text, offset, keyValue)
+				}
+				h.Context, h.DecodedBody = keyValue.Context, decoded
+				hits = append(hits, h)
+			}
+		}
+		bodyHits(text, false)
+		if decoded := decodedBodyText(text); decoded != "" {
+			bodyHits(decoded, true)
+		}
+	}
+	if err := <-errChan; err != nil {
+		return err
+	}
+	return encodeJSON(w, hits)
+}
+
+// Offsets of every occurrence of substr in s, none for an empty substr
+func indexAll(s, substr string) []int {
+	offsets := []int{}
+	for from := 0; substr != ""; {
+		i := strings.Index(s[from:], substr)
+		if i == -1 {
+			break
+		}
+		offsets = append(offsets, from+i)
+		from += i + 1
+	}
+	return offsets
+}
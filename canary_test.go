@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteCanary(t *testing.T) {
+	defer func(canary string) { *canaryFlag = canary }(*canaryFlag)
+	*canaryFlag = "zz9canary"
+	path := filepath.Join(t.TempDir(), "canary.har")
+	har := `{"log":{"entries":[
+		{"request":{"method":"GET","url":"https://example.com/a"},"response":{"content":{"mimeType":"application/json","text":"{\"n\":\"zz9canary\"}"}}},
+		{"request":{"method":"GET","url":"https://example.com/b"},"response":{"content":{"mimeType":"text/html","text":"<a href=\"zz9canary\">zz9canary</a>"}}}
+	]}}`
+	if err := os.WriteFile(path, []byte(har), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := bytes.Buffer{}
+	if err := writeCanary([]string{path}, &out); err != nil {
+		t.Fatal(err)
+	}
+	hits := []*CanaryHit{}
+	if err := json.Unmarshal(out.Bytes(), &hits); err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		entry   int
+		context string
+	}{{0, ""}, {1, "url-attribute"}, {1, "text"}}
+	if len(hits) != len(want) {
+		t.Fatalf("writeCanary = %s, want %d hits", out.String(), len(want))
+	}
+	for i, hit := range hits {
+		if hit.File != path || hit.Entry != want[i].entry || hit.Context != want[i].context {
+			t.Errorf("hit %d = %+v, want entry %d in %s context %q", i, hit, want[i].entry, path, want[i].context)
+		}
+	}
+}
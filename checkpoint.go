@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How often a checkpoint is saved as entries finish
+const checkpointInterval = time.Second
+
+// Index of the next entry to scan, persisted to a file so an interrupted run
+// can resume. A nil checkpoint does nothing.
+type checkpoint struct {
+	path  string
+	next  int
+	saved time.Time
+}
+
+// Loads the checkpoint at path, a missing file starts from the beginning
+func loadCheckpoint(path string) (*checkpoint, error) {
+	if path == "" {
+		return nil, nil
+	}
+	cp := &checkpoint{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if cp.next, err = strconv.Atoi(strings.TrimSpace(string(data))); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+func (cp *checkpoint) Next() int {
+	if cp == nil {
+		return 0
+	}
+	return cp.next
+}
+
+// Records that every entry before next is done, saving at most once per
+// checkpointInterval
+func (cp *checkpoint) Advance(next int) error {
+	if cp == nil || time.Since(cp.saved) < checkpointInterval {
+		return nil
+	}
+	return cp.Save(next)
+}
+
+// Saves via a rename so an interrupted write never leaves a corrupt file
+func (cp *checkpoint) Save(next int) error {
+	if cp == nil {
+		return nil
+	}
+	cp.next, cp.saved = next, time.Now()
+	tmp := cp.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.Itoa(next)+"\n"), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, cp.path)
+}
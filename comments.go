@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"sort"
+	"strings"
+)
+
+var includeCommentsFlag = flag.Bool("include-comments", false, "Also search the entry comment and -custom-fields for values, as if they were request params")
+var customFieldsFlag = flag.String("custom-fields", "", "Space delimited custom underscore fields of entries to search with -include-comments e.g. '_marker _initiator'")
+
+// Parses the entry, keeping the -custom-fields that are present with
+// -include-comments
+func (entry *Entry) UnmarshalJSON(data []byte) error {
+	type plainEntry Entry
+	if err := json.Unmarshal(data, (*plainEntry)(entry)); err != nil {
+		return err
+	}
+	fields := strings.Fields(*customFieldsFlag)
+	if !*includeCommentsFlag || len(fields) == 0 {
+		return nil
+	}
+	all := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	for _, field := range fields {
+		if raw, ok := all[field]; ok && strings.HasPrefix(field, "_") {
+			if entry.Custom == nil {
+				entry.Custom = map[string]json.RawMessage{}
+			}
+			entry.Custom[field] = raw
+		}
+	}
+	return nil
+}
+
+// All the key values of a request, then with -include-comments of the entry
+// comment keyed ["comment"] and custom fields keyed by name e.g. ["_marker"]
+func searchEntry(ctx context.Context, entry *Entry) <-chan *KeyValue {
+	if !*includeCommentsFlag {
+		return searchRequest(ctx, &entry.Request)
+	}
+	keyValueChan := make(chan *KeyValue)
+	go func() {
+		defer close(keyValueChan)
+		for keyValue := range searchRequest(ctx, &entry.Request) {
+			if !send(ctx, keyValueChan, keyValue) {
+				return
+			}
+		}
+		if entry.Comment != "" {
+			for keyValue := range search(ctx, []string{"comment"}, entry.Comment) {
+				keyValue.HarPath = "comment"
+				if !send(ctx, keyValueChan, keyValue) {
+					return
+				}
+			}
+		}
+		// Sorted so output is deterministic
+		fields := make([]string, 0, len(entry.Custom))
+		for field := range entry.Custom {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			// Strings are searched as their value, anything else as json
+			value := string(entry.Custom[field])
+			json.Unmarshal(entry.Custom[field], &value)
+			for keyValue := range search(ctx, []string{field}, value) {
+				keyValue.HarPath = field
+				if !send(ctx, keyValueChan, keyValue) {
+					return
+				}
+			}
+		}
+	}()
+	return keyValueChan
+}
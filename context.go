@@ -0,0 +1,310 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Where in an HTML document a reflection lands
+type htmlContext struct {
+	Kind  string            // text, comment, tag, attribute, script or style
+	Tag   string            // Lowercase name of the tag the offset is in or inside of
+	Attr  string            // Lowercase attribute name when Kind is attribute
+	Attrs map[string]string // Attributes of Tag parsed up to the offset
+	Start int               // Where the attribute value or element text starts
+	End   int               // Where it ends
+}
+
+// Elements whose contents are not parsed as markup
+var rawTextTags = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"textarea": true,
+	"title":    true,
+	"xmp":      true,
+}
+
+// Lexes just enough HTML to tell the context at offset. It is not a full
+// parser, the goal is a cheap and forgiving guess.
+func classify(body string, offset int) htmlContext {
+	for i := 0; i < len(body); {
+		lt := strings.IndexByte(body[i:], '<')
+		if lt < 0 || offset < i+lt {
+			break
+		}
+		i += lt
+
+		// Comments
+		if strings.HasPrefix(body[i:], "<!--") {
+			end := strings.Index(body[i+4:], "-->")
+			if end < 0 {
+				return htmlContext{Kind: "comment", Start: i + 4, End: len(body)}
+			}
+			end += i + 4
+			if offset < end {
+				return htmlContext{Kind: "comment", Start: i + 4, End: end}
+			}
+			i = end + 3
+			continue
+		}
+
+		// Tags
+		closing := strings.HasPrefix(body[i:], "</")
+		nameStart := i + 1
+		if closing {
+			nameStart++
+		}
+		if len(body) <= nameStart || !isASCIILetter(body[nameStart]) {
+			i++
+			continue
+		}
+		ctx, tagEnd := lexTag(body, nameStart, offset)
+		if ctx.Kind != "" {
+			return ctx
+		}
+		i = tagEnd
+		if closing || !rawTextTags[ctx.Tag] {
+			continue
+		}
+		end := indexFold(body[tagEnd:], "</"+ctx.Tag)
+		if end < 0 {
+			end = len(body)
+		} else {
+			end += tagEnd
+		}
+		if offset < end {
+			ctx.Kind = "text"
+			if ctx.Tag == "script" || ctx.Tag == "style" {
+				ctx.Kind = ctx.Tag
+			}
+			ctx.Start, ctx.End = tagEnd, end
+			return ctx
+		}
+		i = end
+	}
+	return htmlContext{Kind: "text"}
+}
+
+// Lexes the tag whose name starts at nameStart. The returned context has a
+// Kind only if offset is inside the tag, the int is where the tag ends.
+func lexTag(body string, nameStart, offset int) (htmlContext, int) {
+	i := nameStart
+	for i < len(body) && !isHTMLSpace(body[i]) && body[i] != '>' && body[i] != '/' {
+		i++
+	}
+	ctx := htmlContext{
+		Tag:   strings.ToLower(body[nameStart:i]),
+		Attrs: map[string]string{},
+	}
+	for i < len(body) {
+		for i < len(body) && (isHTMLSpace(body[i]) || body[i] == '/') {
+			i++
+		}
+		if len(body) <= i || body[i] == '>' {
+			break
+		}
+		attrStart := i
+		for i < len(body) && !isHTMLSpace(body[i]) && !strings.ContainsRune("=>/", rune(body[i])) {
+			i++
+		}
+		attr := strings.ToLower(body[attrStart:i])
+		for i < len(body) && isHTMLSpace(body[i]) {
+			i++
+		}
+		if len(body) <= i || body[i] != '=' {
+			ctx.Attrs[attr] = ""
+			continue
+		}
+		i++
+		for i < len(body) && isHTMLSpace(body[i]) {
+			i++
+		}
+		valueStart, valueEnd := i, i
+		if i < len(body) && (body[i] == '"' || body[i] == '\'') {
+			valueStart++
+			valueEnd = strings.IndexByte(body[valueStart:], body[i])
+			if valueEnd < 0 {
+				valueEnd = len(body)
+			} else {
+				valueEnd += valueStart
+			}
+			i = valueEnd + 1
+		} else {
+			for valueEnd < len(body) && !isHTMLSpace(body[valueEnd]) && body[valueEnd] != '>' {
+				valueEnd++
+			}
+			i = valueEnd
+		}
+		if valueStart <= offset && offset < valueEnd {
+			ctx.Kind, ctx.Attr, ctx.Start, ctx.End = "attribute", attr, valueStart, valueEnd
+			return ctx, i
+		}
+		ctx.Attrs[attr] = body[valueStart:min(valueEnd, len(body))]
+	}
+	end := min(i+1, len(body))
+	if offset < end {
+		ctx.Kind, ctx.Start, ctx.End = "tag", nameStart, end
+	}
+	return ctx, end
+}
+
+// Attributes whose value is a URL
+var urlAttrs = map[string]bool{
+	"href":       true,
+	"src":        true,
+	"action":     true,
+	"formaction": true,
+	"xlink:href": true, // SVG
+}
+
+// Sets the Context of a reflection at offset, plus what else is known about
+// it e.g. for data blocks like <script type="application/json"> the path of the
+// JSON field the value reflects in
+func describeContext(body string, offset int, keyValue *KeyValue) {
+	ctx := classify(body, offset)
+	switch {
+	// Nested documents, markup reflected into them renders
+	case ctx.Kind == "attribute" && ctx.Attr == "srcdoc":
+		keyValue.Context = "srcdoc"
+	case inDataURI(body, offset):
+		keyValue.Context = "data-uri"
+
+	// Open redirects, and javascript: if the value starts the URL
+	case ctx.Kind == "attribute" && urlAttrs[ctx.Attr]:
+		keyValue.Context = "url-attribute"
+		keyValue.URLStart = strings.TrimLeft(body[ctx.Start:offset], " \t\n\f\r") == ""
+		if keyValue.URLStart {
+			keyValue.URLScheme = scriptableScheme(keyValue.Value)
+		}
+
+	case ctx.Kind == "script" && isJSONScript(ctx.Attrs["type"]):
+		keyValue.Context = "json-script"
+		keyValue.JSONPath, _ = jsonPath(body[ctx.Start:ctx.End], offset-ctx.Start)
+	default:
+		keyValue.Context = ctx.Kind
+	}
+
+	// Reached server side rendering of the page's own metadata
+	switch {
+	case ctx.Tag == "title" && ctx.Kind == "text":
+		keyValue.Element = "title"
+	case ctx.Tag == "meta" && (ctx.Kind == "attribute" || ctx.Kind == "tag"):
+		keyValue.Element = "meta"
+	}
+}
+
+// URL schemes that run or render whatever follows them
+var scriptableSchemes = []string{"javascript", "data", "blob"}
+
+// The scriptable scheme a URL starts with, if any, the way browsers parse it
+// ignoring leading spaces and tabs or newlines anywhere in the scheme
+func scriptableScheme(rawURL string) string {
+	rawURL = strings.TrimLeft(rawURL, "\x00 \t\n\f\r")
+	scheme, _, ok := strings.Cut(strings.NewReplacer("\t", "", "\n", "", "\r", "").Replace(rawURL), ":")
+	if !ok {
+		return ""
+	}
+	scheme = strings.ToLower(scheme)
+	for _, s := range scriptableSchemes {
+		if scheme == s {
+			return s
+		}
+	}
+	return ""
+}
+
+// Like describeContext for an SVG document, where element text and markup
+// are svg since injected elements render with script like in HTML
+func describeSVGContext(body string, offset int, keyValue *KeyValue) {
+	describeContext(body, offset, keyValue)
+	switch keyValue.Context {
+	case "text", "tag", "comment":
+		keyValue.Context = "svg"
+	}
+}
+
+// Whether offset is in a data: URI, looking back to the start of the URI
+// token wherever it is e.g. an attribute or a css url()
+func inDataURI(body string, offset int) bool {
+	start := strings.LastIndexAny(body[:offset], " \t\n\f\r\"'()<>")
+	return strings.HasPrefix(strings.ToLower(body[start+1:offset]), "data:")
+}
+
+func isJSONScript(mimeType string) bool {
+	return isJSONMimeType(strings.ToLower(strings.TrimSpace(mimeType)))
+}
+
+// Dot delimited path to the string, or object key, of JSON text the byte at
+// offset is in
+func jsonPath(text string, offset int) (string, bool) {
+	type frame struct {
+		array     bool
+		index     int    // Of the current element of an array
+		key       string // Of the current member of an object
+		expectKey bool
+	}
+	path := func(stack []*frame) string {
+		elems := make([]string, len(stack))
+		for i, f := range stack {
+			if f.array {
+				elems[i] = strconv.Itoa(f.index)
+			} else {
+				elems[i] = f.key
+			}
+		}
+		return strings.Join(elems, ".")
+	}
+	dec := json.NewDecoder(strings.NewReader(text))
+	stack := []*frame{}
+	for {
+		start := int(dec.InputOffset())
+		token, err := dec.Token()
+		if err != nil {
+			return "", false
+		}
+		end := int(dec.InputOffset())
+		var parent *frame
+		if 0 < len(stack) {
+			parent = stack[len(stack)-1]
+		}
+		if delim, ok := token.(json.Delim); ok && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		if parent != nil && !parent.array && parent.expectKey {
+			parent.key, parent.expectKey = token.(string), false
+			if start <= offset && offset < end {
+				return path(stack), true
+			}
+			continue
+		}
+		// The start of a value, of the parent's next element or member
+		if parent != nil && parent.array {
+			parent.index++
+		} else if parent != nil {
+			parent.expectKey = true
+		}
+		switch token := token.(type) {
+		case json.Delim:
+			stack = append(stack, &frame{array: token == '[', index: -1, expectKey: token == '{'})
+		case string:
+			if start <= offset && offset < end {
+				return path(stack), true
+			}
+		}
+	}
+}
+
+func isASCIILetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isHTMLSpace(c byte) bool {
+	return strings.IndexByte(" \t\n\f\r", c) != -1
+}
+
+// Case insensitive strings.Index for an ASCII substr
+func indexFold(s, substr string) int {
+	return strings.Index(strings.ToLower(s), strings.ToLower(substr))
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		body, value string
+		kind, tag   string
+		attr        string
+	}{
+		{`<p>VALUE</p>`, "VALUE", "text", "", ""},
+		{`<a href="VALUE">`, "VALUE", "attribute", "a", "href"},
+		{`<a href='x VALUE'>`, "VALUE", "attribute", "a", "href"},
+		{`<input value=VALUE>`, "VALUE", "attribute", "input", "value"},
+		{`<div VALUE>`, "VALUE", "tag", "div", ""},
+		{`<!-- VALUE -->`, "VALUE", "comment", "", ""},
+		{`<script>var x = "VALUE"</script>`, "VALUE", "script", "script", ""},
+		{`<STYLE>VALUE</STYLE>`, "VALUE", "style", "style", ""},
+		{`<title><b>VALUE</b></title>`, "VALUE", "text", "title", ""},
+		{`<textarea></div>VALUE</textarea>`, "VALUE", "text", "textarea", ""},
+		{`<script>"</p>"</script><p>VALUE`, "VALUE", "text", "", ""},
+		{`1 < 2 VALUE`, "VALUE", "text", "", ""},
+		{`<!-- unterminated VALUE`, "VALUE", "comment", "", ""},
+	}
+	for _, test := range tests {
+		offset := indexOf(t, test.body, test.value)
+		ctx := classify(test.body, offset)
+		if ctx.Kind != test.kind || ctx.Tag != test.tag || ctx.Attr != test.attr {
+			t.Errorf("classify(%q) = %s %q %q, want %s %q %q", test.body, ctx.Kind, ctx.Tag, ctx.Attr, test.kind, test.tag, test.attr)
+		}
+	}
+}
+
+func TestDescribeContext(t *testing.T) {
+	tests := []struct {
+		body, value string
+		context     string
+		urlStart    bool
+		urlScheme   string
+		jsonPath    string
+	}{
+		{`<a href="VALUE">`, "VALUE", "url-attribute", true, "", ""},
+		{`<a href="/x?VALUE">`, "VALUE", "url-attribute", false, "", ""},
+		{`<a href=" javascript:VALUE">`, " javascript:VALUE", "url-attribute", true, "javascript", ""},
+		{`<iframe srcdoc="VALUE">`, "VALUE", "srcdoc", false, "", ""},
+		{`<img src="data:text/html,VALUE">`, "VALUE", "data-uri", false, "", ""},
+		{`<script type="application/json">{"a":{"b":"xVALUE"}}</script>`, "VALUE", "json-script", false, "", "a.b"},
+		{`<script type="application/ld+json">{"z":"VALUE","b":"VALUE"}</script>`, "VALUE", "json-script", false, "", "z"},
+	}
+	for _, test := range tests {
+		keyValue := &KeyValue{Value: test.value}
+		describeContext(test.body, indexOf(t, test.body, test.value), keyValue)
+		if keyValue.Context != test.context || keyValue.URLStart != test.urlStart || keyValue.URLScheme != test.urlScheme || keyValue.JSONPath != test.jsonPath {
+			t.Errorf("describeContext(%q) = %s %t %q %q, want %s %t %q %q", test.body, keyValue.Context, keyValue.URLStart, keyValue.URLScheme, keyValue.JSONPath, test.context, test.urlStart, test.urlScheme, test.jsonPath)
+		}
+	}
+}
+
+func indexOf(t *testing.T, body, value string) int {
+	t.Helper()
+	for i := 0; i+len(value) <= len(body); i++ {
+		if body[i:i+len(value)] == value {
+			return i
+		}
+	}
+	t.Fatalf("%q not in %q", value, body)
+	return -1
+}
+
+func TestJSONPath(t *testing.T) {
+	// The offset is that of the last byte of at
+	tests := []struct {
+		text, at string
+		path     string
+		ok       bool
+	}{
+		{`{"a":"x"}`, `"x`, "a", true},
+		{`{"a":{"b":["x","y"]}}`, `"y`, "a.b.1", true},
+		{`[{"a":"x"},{"b":"VALUE"}]`, "VAL", "1.b", true},
+		{`{"zeta":"VALUE","b":"VALUE"}`, `"b":"VAL`, "b", true},
+		{`{"n":1,"m":[1,{"k":2}],"s":"VALUE"}`, "VAL", "s", true},
+		{`{"VALUE":1}`, "VAL", "VALUE", true},
+		{`{"a":12345}`, "234", "", false},
+		{`not json VALUE`, "VAL", "", false},
+	}
+	for _, test := range tests {
+		offset := indexOf(t, test.text, test.at) + len(test.at) - 1
+		path, ok := jsonPath(test.text, offset)
+		if path != test.path || ok != test.ok {
+			t.Errorf("jsonPath(%q, at %q) = %q, %t, want %q, %t", test.text, test.at, path, ok, test.path, test.ok)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+)
+
+// Whether a Content-Security-Policy keeps injected markup from running
+// script, i.e. its script-src, or default-src without one, allows neither
+// inline script nor arbitrary hosts. Nonces, hashes and 'strict-dynamic'
+// make browsers ignore 'unsafe-inline'.
+func isStrictCSP(policy string) bool {
+	directives := map[string][]string{}
+	for _, directive := range strings.Split(policy, ";") {
+		if fields := strings.Fields(strings.ToLower(directive)); 0 < len(fields) {
+			// Browsers use the first of a repeated directive
+			if _, ok := directives[fields[0]]; !ok {
+				directives[fields[0]] = fields[1:]
+			}
+		}
+	}
+	sources, ok := directives["script-src"]
+	if !ok {
+		if sources, ok = directives["default-src"]; !ok {
+			return false
+		}
+	}
+	unsafeInline, overridesInline := false, false
+	for _, source := range sources {
+		switch {
+		case source == "'unsafe-inline'":
+			unsafeInline = true
+		case source == "'strict-dynamic'", strings.HasPrefix(source, "'nonce-"), strings.HasPrefix(source, "'sha"):
+			overridesInline = true
+		case source == "*", source == "data:", source == "http:", source == "https:":
+			return false
+		}
+	}
+	return !unsafeInline || overridesInline
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestIsStrictCSP(t *testing.T) {
+	tests := []struct {
+		policy string
+		strict bool
+	}{
+		{"", false},
+		{"img-src 'self'", false},
+		{"script-src 'self'", true},
+		{"default-src 'self'", true},
+		{"script-src 'self' 'unsafe-inline'", false},
+		{"script-src 'unsafe-inline' 'nonce-abc'", true},
+		{"script-src 'unsafe-inline' 'strict-dynamic'", true},
+		{"script-src 'unsafe-inline' 'sha256-abc='", true},
+		{"script-src https:", false},
+		{"script-src *", false},
+		{"script-src data:", false},
+		{"default-src 'self'; script-src 'unsafe-inline'", false},
+		{"script-src 'self'; script-src 'unsafe-inline'", true},
+		{"SCRIPT-SRC 'SELF'", true},
+	}
+	for _, test := range tests {
+		if strict := isStrictCSP(test.policy); strict != test.strict {
+			t.Errorf("isStrictCSP(%q) = %t, want %t", test.policy, strict, test.strict)
+		}
+	}
+}
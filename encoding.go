@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/quotedprintable"
+	"net/http/httputil"
+	"strings"
+	"unicode"
+)
+
+// Decompressors by Content-Encoding token, more can be registered from init e.g. brotli.go
+var contentDecoders = map[string]func(io.Reader) (io.Reader, error){
+	"gzip": func(r io.Reader) (io.Reader, error) {
+		return gzip.NewReader(r)
+	},
+	"deflate": func(r io.Reader) (io.Reader, error) {
+		return flate.NewReader(r), nil
+	},
+}
+
+// Returns the value of the first header with the given name, case insensitive
+func header(headers []Header, name string) string {
+	for _, h := range headers {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+// Strips chunk size lines that some proxies leave in bodies stored with
+// Transfer-Encoding: chunked, anything that isn't validly chunked is returned
+// as is
+func dechunk(body []byte) []byte {
+	dechunked, err := io.ReadAll(httputil.NewChunkedReader(bytes.NewReader(body)))
+	if err != nil {
+		return body
+	}
+	return dechunked
+}
+
+// Undoes the Content-Encoding of a response body. Browsers usually store the
+// body already decoded even though the header is still present, so anything
+// that fails to decode is returned as is.
+func decodeContent(encoding string, body []byte) []byte {
+	// Encodings are listed in the order they were applied
+	encodings := strings.Split(encoding, ",")
+	decoded := body
+	for i := len(encodings) - 1; 0 <= i; i-- {
+		newReader, ok := contentDecoders[strings.ToLower(strings.TrimSpace(encodings[i]))]
+		if !ok {
+			continue
+		}
+		r, err := newReader(bytes.NewReader(decoded))
+		if err != nil {
+			return body
+		}
+		if decoded, err = readDecoded(r); err != nil {
+			return body
+		}
+	}
+	return decoded
+}
+
+// Decodes a body stored with Content-Transfer-Encoding: quoted-printable, as
+// in captures of email, anything that isn't valid quoted-printable is
+// returned as is
+func decodeQuotedPrintable(body []byte) []byte {
+	decoded, err := io.ReadAll(quotedprintable.NewReader(bytes.NewReader(body)))
+	if err != nil {
+		return body
+	}
+	return decoded
+}
+
+// Some tools store a gzipped body base64 encoded once more on top of the
+// HAR's own base64, this decodes that or returns the body as is
+func gunzipBase64(body []byte) []byte {
+	gzipped, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(body)))
+	if err != nil || !bytes.HasPrefix(gzipped, gzipMagic) {
+		return body
+	}
+	r, err := gzip.NewReader(bytes.NewReader(gzipped))
+	if err != nil {
+		return body
+	}
+	decoded, err := readDecoded(r)
+	if err != nil {
+		return body
+	}
+	return decoded
+}
+
+// Reads a decompressed body, with -serve failing past -serve-max-bytes so a
+// small compression bomb in a POSTed .har can't take all the memory
+func readDecoded(r io.Reader) ([]byte, error) {
+	if *serveFlag == "" {
+		return io.ReadAll(r)
+	}
+	decoded, err := io.ReadAll(io.LimitReader(r, *serveMaxBytesFlag+1))
+	if err == nil && *serveMaxBytesFlag < int64(len(decoded)) {
+		return nil, fmt.Errorf("decompressed body larger than -serve-max-bytes %d", *serveMaxBytesFlag)
+	}
+	return decoded, err
+}
+
+// A body that is itself base64, line breaks and all, decoded if it decodes
+// to mostly printable text, otherwise the body as is
+func decodeBase64Body(body []byte) []byte {
+	encoded := strings.Join(strings.Fields(string(body)), "")
+	decoded, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil || len(decoded) == 0 || !isMostlyPrintable(string(decoded)) {
+		return body
+	}
+	slog.Debug("Decoded base64 body")
+	return decoded
+}
+
+// First bytes of gzip data
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// The Content-Encoding an exporter applied to the stored body on top of the
+// response's own, from content._compressed or a content.comment like
+// "compressed with gzip", empty if there is none
+func compressionHint(response *Response, body []byte) string {
+	if encoding := ""; json.Unmarshal(response.Content.Compressed, &encoding) == nil && encoding != "" {
+		return encoding
+	}
+	if string(response.Content.Compressed) == "true" {
+		// Unspecified, either has to be gzip or raw deflate
+		if bytes.HasPrefix(body, gzipMagic) {
+			return "gzip"
+		}
+		return "deflate"
+	}
+	comment := strings.ToLower(response.Content.Comment)
+	if !strings.Contains(comment, "compress") {
+		return ""
+	}
+	for _, word := range strings.FieldsFunc(comment, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if _, ok := contentDecoders[word]; ok {
+			return word
+		}
+	}
+	return ""
+}
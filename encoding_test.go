@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func TestDecodeContentServeMaxBytes(t *testing.T) {
+	defer func(serve string, maxBytes int64) { *serveFlag, *serveMaxBytesFlag = serve, maxBytes }(*serveFlag, *serveMaxBytesFlag)
+	gzipped := bytes.Buffer{}
+	w := gzip.NewWriter(&gzipped)
+	w.Write(bytes.Repeat([]byte("a"), 1<<20))
+	w.Close()
+	for _, test := range []struct {
+		serve    string
+		maxBytes int64
+		size     int
+	}{
+		{"", 1 << 10, 1 << 20},
+		{":8080", 1 << 20, 1 << 20},
+		{":8080", 1<<20 - 1, gzipped.Len()}, // Left as is
+	} {
+		*serveFlag, *serveMaxBytesFlag = test.serve, test.maxBytes
+		if decoded := decodeContent("gzip", gzipped.Bytes()); len(decoded) != test.size {
+			t.Errorf("decodeContent with -serve %q -serve-max-bytes %d = %d bytes, want %d", test.serve, test.maxBytes, len(decoded), test.size)
+		}
+	}
+}
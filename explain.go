@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// Level decisions on entries and values are logged at, info with -explain
+// so they show without all of -log-level debug
+func explainLevel() slog.Level {
+	if *explainFlag {
+		return slog.LevelInfo
+	}
+	return slog.LevelDebug
+}
+
+// Logs why a value of entry was or wasn't a finding
+func explain(entry *Entry, keyValue *KeyValue, reason string) {
+	if !*explainFlag {
+		return
+	}
+	// Logs don't get redacted like results, so leave values out, reasons
+	// never include them
+	if *redactFlag {
+		slog.Info("Explained value", "url", entry.Request.URL, "key", keyValue.Path(), "reason", reason)
+	} else {
+		slog.Info("Explained value", "url", entry.Request.URL, "key", keyValue.Path(), "value", keyValue.Value, "reason", reason)
+	}
+}
+
+// How a matched keyValue reflects
+func explainMatch(keyValue *KeyValue) string {
+	switch {
+	case keyValue.Forced:
+		return "doesn't reflect, matched anyway for -no-body-match"
+	case keyValue.Finding == "ssti":
+		return "template expressions reflect evaluated"
+	case *matchRegexFlag != "":
+		return "matches -match-regex"
+	case keyValue.Escaping == "base64":
+		return "reflects base64 encoded, in a data: URI or with -body-base64"
+	case keyValue.Escaping != "":
+		return fmt.Sprintf("reflects %s escaped", keyValue.Escaping)
+	case keyValue.Normalization != "":
+		return fmt.Sprintf("reflects after %s normalization", keyValue.Normalization)
+	case keyValue.Dropped != "":
+		return fmt.Sprintf("reflects with %d bytes dropped", len(keyValue.Dropped))
+	case keyValue.Fuzzy:
+		return "reflects split by HTML tags"
+	case keyValue.Whitespace:
+		return "reflects in an attribute with other whitespace"
+	case keyValue.Distance != 0:
+		return fmt.Sprintf("reflects with %d edits", keyValue.Distance)
+	}
+	return "reflects as is"
+}
+
+// Why keyValue isn't a finding
+func explainMiss(keyValue *KeyValue) string {
+	switch {
+	case keyValue.Value == "":
+		return "empty value"
+	case *matchRegexFlag != "":
+		return "doesn't match -match-regex"
+	case 0 < *fuzzyDistanceFlag && len(keyValue.Value) < *fuzzyMinLenFlag:
+		return "doesn't reflect, and is shorter than -fuzzy-min-len for fuzzy matching"
+	}
+	return "doesn't reflect"
+}
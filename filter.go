@@ -0,0 +1,217 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Flag that can be repeated, collecting every value
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+var paramValueRegexFlag = flag.String("param-value-regex", "", "Only report values, after decoding, that match this regex e.g. '[<>\"]|https?:' for ones that look like payloads")
+
+// Compiled -param-value-regex, nil without one
+var paramValueRegexp *regexp.Regexp
+
+// Compiles -param-value-regex and adds it to the findingFilters
+func setupParamValueRegex() error {
+	if *paramValueRegexFlag == "" {
+		return nil
+	}
+	var err error
+	if paramValueRegexp, err = regexp.Compile(*paramValueRegexFlag); err != nil {
+		return err
+	}
+	findingFilters = append(findingFilters, func(keyValue *KeyValue) bool {
+		return paramValueRegexp.MatchString(keyValue.Value)
+	})
+	return nil
+}
+
+// Why an entry is filtered out, empty if it passes every filter
+func filterEntry(entry *Entry) (string, error) {
+	return filterEntryWith(entry, true)
+}
+
+// Like filterEntry, but -content-types only applies if byContentType
+func filterEntryWith(entry *Entry, byContentType bool) (string, error) {
+	if domains := strings.Fields(*domainsFlag); 0 < len(domains) {
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			return "", err
+		}
+		// Relative URLs that couldn't be resolved are scanned rather than
+		// silently dropped
+		ok := u.Host == ""
+		for _, domain := range domains {
+			if matchDomain(domain, u.Host) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return "domain not in -domains", nil
+		}
+	}
+	if *skipCachedFlag && isFromCache(&entry.Response) {
+		return "served from cache, -skip-cached", nil
+	}
+	if *skipCachedFlag && isBodyMissing(&entry.Response) {
+		return "body not captured, -skip-cached", nil
+	}
+	mimeType := responseMimeType(&entry.Response)
+	if *skipStaticFlag && isStatic(entry.Request.URL, mimeType) {
+		return "static asset, -skip-static", nil
+	}
+	// JSON types, and with -xml the XML ones, are scanned on top of whatever
+	// -content-types allows
+	if contentTypes := strings.Fields(*contentTypesFlag); byContentType && 0 < len(contentTypes) && !matchMimeType(mimeType, contentTypes) && !isJSONMimeType(mimeType) && !(*xmlFlag && isXMLMimeType(mimeType)) {
+		return fmt.Sprintf("content type %q not in -content-types", mimeType), nil
+	}
+	if *userAgentContainsFlag != "" && !strings.Contains(header(entry.Request.Headers, "User-Agent"), *userAgentContainsFlag) {
+		return "user agent doesn't contain -user-agent-contains", nil
+	}
+	for _, filter := range headerFilters {
+		if !matchHeaderFilter(entry.Request.Headers, filter, false) {
+			return fmt.Sprintf("request headers don't match -header-filter %q", filter), nil
+		}
+	}
+	for _, filter := range headerMatches {
+		if !matchHeaderFilter(entry.Request.Headers, filter, true) {
+			return fmt.Sprintf("request headers don't match -header-match %q", filter), nil
+		}
+	}
+	for _, filter := range responseHeaderFilters {
+		if !matchResponseHeaderFilter(entry.Response.Headers, filter) {
+			return fmt.Sprintf("response headers don't match -response-header-filter %q", filter), nil
+		}
+	}
+	return "", nil
+}
+
+// Like matchHeaderFilter for substrings, but "!Name" matches when there is
+// no such header
+func matchResponseHeaderFilter(headers []Header, filter string) bool {
+	if name, ok := strings.CutPrefix(strings.TrimSpace(filter), "!"); ok {
+		return !matchHeaderFilter(headers, name, false)
+	}
+	return matchHeaderFilter(headers, filter, false)
+}
+
+// Whether a -domains domain matches the host of a URL, case insensitive.
+// Either may have a port and IPv6 addresses may be in brackets, a domain
+// without a port matches any.
+func matchDomain(domain, host string) bool {
+	domainHost, domainPort := splitHostPort(domain)
+	hostHost, hostPort := splitHostPort(host)
+	return strings.EqualFold(domainHost, hostHost) && (domainPort == "" || domainPort == hostPort)
+}
+
+// Like net.SplitHostPort but the port is optional
+func splitHostPort(hostport string) (string, string) {
+	if host, port, err := net.SplitHostPort(hostport); err == nil {
+		return host, port
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(hostport, "["), "]"), ""
+}
+
+// Whether a request is for a static asset by its path extension or response
+// mime type
+func isStatic(rawURL, mimeType string) bool {
+	if matchMimeType(mimeType, strings.Fields(*staticMimeTypesFlag)) {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	ext := strings.ToLower(path.Ext(u.Path))
+	for _, staticExt := range strings.Fields(strings.ToLower(*staticExtensionsFlag)) {
+		if ext != "" && ext == staticExt {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether some header matches a "Name: value" filter, containing the value
+// or being exactly it. A filter without a value only needs the header to be
+// present.
+func matchHeaderFilter(headers []Header, filter string, exact bool) bool {
+	name, value, hasValue := strings.Cut(filter, ":")
+	name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+	for _, h := range headers {
+		if !strings.EqualFold(h.Name, name) {
+			continue
+		}
+		matches := strings.Contains(h.Value, value)
+		if exact && hasValue {
+			matches = strings.TrimSpace(h.Value) == value
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// Lowercase mime type of the response without parameters like charset,
+// falling back to the Content-Type header
+func responseMimeType(response *Response) string {
+	mimeType := response.Content.MimeType
+	if mimeType == "" {
+		mimeType = header(response.Headers, "Content-Type")
+	}
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+// Whether mimeType is one of patterns, which may end in a wildcard e.g. text/*
+func matchMimeType(mimeType string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if pattern == mimeType || (strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mimeType, pattern[:len(pattern)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether the response had a body the HAR doesn't have, like for cache hits
+// some browsers record without content
+func isBodyMissing(response *Response) bool {
+	return response.Content.Text == "" && 0 < response.Content.Size
+}
+
+// Whether the response was served from the browser's cache
+func isFromCache(response *Response) bool {
+	switch string(response.FromCache) {
+	case "", "null", "false", `""`:
+		return false
+	}
+	return true
+}
+
+// Result for an entry that wasn't scanned
+func skipped(entry *Entry, reason string) *Result {
+	return &Result{
+		Method:  entry.Request.Method,
+		URL:     entry.Request.URL,
+		XSS:     []*KeyValue{},
+		Skipped: reason,
+	}
+}
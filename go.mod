@@ -1,3 +1,8 @@
-module main
+module github.com/mgbelisle/har2xss
 
-go 1.17
+go 1.21
+
+require (
+	github.com/andybalholm/brotli v1.1.1
+	golang.org/x/text v0.14.0
+)
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type graphQLOperation struct {
+	Query     string          `json:"query"`
+	Variables json.RawMessage `json:"variables"`
+}
+
+// Parses a json post of a graphql operation, or a batch of them
+func parseGraphQL(postData *PostData) ([]graphQLOperation, bool) {
+	if !strings.Contains(strings.ToLower(postData.MimeType), "json") {
+		return nil, false
+	}
+	text := []byte(strings.TrimSpace(postData.Text))
+	operations := []graphQLOperation{}
+	if err := json.Unmarshal(text, &operations); err != nil {
+		operation := graphQLOperation{}
+		if err := json.Unmarshal(text, &operation); err != nil {
+			return nil, false
+		}
+		operations = append(operations, operation)
+	}
+	for _, operation := range operations {
+		if operation.Query == "" {
+			return nil, false
+		}
+	}
+	return operations, 0 < len(operations)
+}
+
+// String arguments written inline in a query e.g. user(name: "bob")
+var graphQLStringArgRegexp = regexp.MustCompile(`(\w+)\s*:\s*("(?:[^"\\]|\\.)*")`)
+
+// Searches graphql variables keyed graphql.variables.<name> and inline string
+// arguments keyed graphql.query.<name>, batches are prefixed with the index
+func searchGraphQL(ctx context.Context, operations []graphQLOperation) <-chan *KeyValue {
+	keyValueChan := make(chan *KeyValue)
+	go func() {
+		defer close(keyValueChan)
+		for i, operation := range operations {
+			prefix := []string{"graphql"}
+			if 1 < len(operations) {
+				prefix = append(prefix, strconv.Itoa(i))
+			}
+			if 0 < len(operation.Variables) && string(operation.Variables) != "null" {
+				for keyValue := range search(ctx, appendKey(prefix, "variables"), string(operation.Variables)) {
+					if !send(ctx, keyValueChan, keyValue) {
+						return
+					}
+				}
+			}
+			for _, match := range graphQLStringArgRegexp.FindAllStringSubmatch(operation.Query, -1) {
+				value := ""
+				if err := json.Unmarshal([]byte(match[2]), &value); err != nil {
+					continue
+				}
+				for keyValue := range search(ctx, appendKey(prefix, "query", match[1]), value) {
+					if !send(ctx, keyValueChan, keyValue) {
+						return
+					}
+				}
+			}
+		}
+	}()
+	return keyValueChan
+}
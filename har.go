@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Subset of the HAR 1.2 spec http://www.softwareishard.com/blog/har-12-spec/
+type HAR struct {
+	Log struct {
+		Version string `json:"version"`
+		Creator struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"creator"`
+		Entries []*Entry `json:"entries"`
+	} `json:"log"`
+}
+
+type Entry struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+	Comment  string   `json:"comment"`
+
+	// Custom underscore fields, only those in -custom-fields
+	Custom map[string]json.RawMessage `json:"-"`
+
+	// With -follow-redirects, the redirects that led here in order
+	redirectedFrom []redirectSource
+}
+
+type Request struct {
+	Method      string   `json:"method"`
+	URL         string   `json:"url"`
+	Headers     []Header `json:"headers"`
+	QueryString []Param  `json:"queryString"`
+	PostData    PostData `json:"postData"`
+	BodySize    int      `json:"bodySize"` // -1 when unknown
+}
+
+type PostData struct {
+	MimeType string  `json:"mimeType"`
+	Params   []Param `json:"params"`
+	Text     string  `json:"text"`
+}
+
+type Response struct {
+	Status   int      `json:"status"`
+	Headers  []Header `json:"headers"`
+	BodySize int      `json:"bodySize"` // -1 when unknown
+	Content  struct {
+		Size     int         `json:"size"`
+		MimeType string      `json:"mimeType"`
+		Text     contentText `json:"text"`
+		Encoding string      `json:"encoding"`
+		Comment  string      `json:"comment"`
+		// Some proxies compress each body within an uncompressed HAR and
+		// mark it with true or the encoding e.g. "gzip"
+		Compressed json.RawMessage `json:"_compressed"`
+	} `json:"content"`
+	// Chrome's "disk" or "memory" when served from cache, other tools use a
+	// bool
+	FromCache json.RawMessage `json:"_fromCache"`
+	// Where a redirect points, some tools only record it here and not in a
+	// Location header
+	RedirectURL string `json:"redirectURL"`
+}
+
+type Param struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+
+	// Of multipart file uploads
+	FileName    string `json:"fileName"`
+	ContentType string `json:"contentType"`
+}
+
+type Header struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// A response body, which a few exporters store as an array of chunks
+type contentText string
+
+func (t *contentText) UnmarshalJSON(data []byte) error {
+	s := ""
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = contentText(s)
+		return nil
+	}
+	chunks := []string{}
+	if err := json.Unmarshal(data, &chunks); err != nil {
+		return err
+	}
+	*t = contentText(strings.Join(chunks, ""))
+	return nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Entry and its index in the capture
+type indexedEntry struct {
+	i     int
+	entry *Entry
+	file  string // When reading files instead of stdin
+}
+
+// Sends the entries of each of paths in turn, or of stdin without any
+func readFiles(ctx context.Context, paths []string, entries chan<- indexedEntry) error {
+	if len(paths) == 0 {
+		return readEntries(ctx, os.Stdin, 0, entries)
+	}
+	for _, path := range paths {
+		if err := readFile(ctx, path, entries); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func readFile(ctx context.Context, path string, entries chan<- indexedEntry) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fileEntries := make(chan indexedEntry)
+	errChan := make(chan error, 1)
+	go func() {
+		defer close(fileEntries)
+		errChan <- readEntries(ctx, f, 0, fileEntries)
+	}()
+	for e := range fileEntries {
+		e.file = path
+		entries <- e
+	}
+	return <-errChan
+}
+
+// Sends the -entries-range of the input from index resume on, until ctx is
+// done
+func readEntries(ctx context.Context, r io.Reader, resume int, entries chan<- indexedEntry) error {
+	r = skipBOM(r)
+	if *entriesNDJSONFlag || *inputFormatFlag == "jsonl" {
+		return readNDJSONEntries(ctx, r, resume, entries)
+	}
+	parseStart := time.Now()
+	har, err := readInput(r)
+	if err != nil {
+		return fmt.Errorf("parsing HAR: %w", err)
+	}
+	slog.Debug("Parsed HAR", "entries", len(har.Log.Entries), "duration", time.Since(parseStart))
+	if *validateFlag {
+		validate(har)
+	}
+
+	start, end, err := parseRange(*entriesRangeFlag, len(har.Log.Entries))
+	if err != nil {
+		return fmt.Errorf("invalid -entries-range: %w", err)
+	}
+	if start < resume {
+		slog.Info("Resuming from checkpoint", "index", resume)
+		start = min(resume, end)
+	}
+	links := redirectLinker{}
+	for i := start; i < end; i++ {
+		resolveURL(har.Log.Entries[i])
+		links.link(i, har.Log.Entries[i])
+		if !sendEntry(ctx, entries, indexedEntry{i: i, entry: har.Log.Entries[i]}) {
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Skips a UTF-8 byte order mark at the start of r, like Chrome's "Copy all as
+// HAR" sometimes has. Whitespace before the JSON is fine as is.
+func skipBOM(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && string(bom) == "\uFEFF" {
+		br.Discard(3)
+	}
+	return br
+}
+
+// Reads the -input-format as a HAR
+func readInput(r io.Reader) (*HAR, error) {
+	switch *inputFormatFlag {
+	case "har":
+		har := &HAR{}
+		return har, json.NewDecoder(r).Decode(har)
+	case "mitmproxy":
+		return readMitmproxy(r)
+	}
+	return nil, fmt.Errorf("unknown -input-format %q", *inputFormatFlag)
+}
+
+// Sends each line as soon as it's read, so there is no end to check the
+// -entries-range against up front
+func readNDJSONEntries(ctx context.Context, r io.Reader, resume int, entries chan<- indexedEntry) error {
+	start, end, err := parseRange(*entriesRangeFlag, math.MaxInt)
+	if err != nil {
+		return fmt.Errorf("invalid -entries-range: %w", err)
+	}
+	start = max(start, resume)
+	br := bufio.NewReader(r)
+	links := redirectLinker{}
+	for i := 0; i < end; i++ {
+		line, err := br.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) == 0 {
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			// Blank lines aren't entries
+			i--
+			continue
+		}
+		if start <= i {
+			entry := &Entry{}
+			if err := json.Unmarshal(line, entry); err != nil && *strictFlag {
+				return fmt.Errorf("parsing entry on line %d: %w", i+1, err)
+			} else if err != nil {
+				slog.Warn("Skipping malformed entry", "index", i, "err", err)
+			} else {
+				resolveURL(entry)
+				links.link(i, entry)
+				if !sendEntry(ctx, entries, indexedEntry{i: i, entry: entry}) {
+					return ctx.Err()
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sends e unless ctx is done first, false if it is
+func sendEntry(ctx context.Context, entries chan<- indexedEntry, e indexedEntry) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	select {
+	case entries <- e:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Makes a relative request URL, which some capture tools record, absolute
+// against the Referer or else -base-url. URLs that can't be resolved are
+// left as is.
+func resolveURL(entry *Entry) {
+	u, err := url.Parse(entry.Request.URL)
+	if err != nil || u.IsAbs() {
+		return
+	}
+	for _, base := range []string{header(entry.Request.Headers, "Referer"), *baseURLFlag} {
+		if baseURL, err := url.Parse(base); err == nil && baseURL.IsAbs() {
+			entry.Request.URL = baseURL.ResolveReference(u).String()
+			return
+		}
+	}
+}
+
+// Parses start:end into entry indices, either side may be omitted
+func parseRange(s string, count int) (int, int, error) {
+	if s == "" {
+		return 0, count, nil
+	}
+	startString, endString, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("expected start:end, got %q", s)
+	}
+	start, end := 0, count
+	var err error
+	if startString != "" {
+		if start, err = strconv.Atoi(startString); err != nil {
+			return 0, 0, err
+		}
+	}
+	if endString != "" {
+		if end, err = strconv.Atoi(endString); err != nil {
+			return 0, 0, err
+		}
+	}
+	if start < 0 || end < start || count < end {
+		return 0, 0, fmt.Errorf("range %d:%d out of bounds for %d entries", start, end, count)
+	}
+	return start, end, nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		s          string
+		count      int
+		start, end int
+		err        bool
+	}{
+		{"", 10, 0, 10, false},
+		{"2:5", 10, 2, 5, false},
+		{":5", 10, 0, 5, false},
+		{"2:", 10, 2, 10, false},
+		{"3:3", 10, 3, 3, false},
+		{"5", 10, 0, 0, true},
+		{"5:2", 10, 0, 0, true},
+		{"0:11", 10, 0, 0, true},
+		{"-1:2", 10, 0, 0, true},
+		{"a:2", 10, 0, 0, true},
+	}
+	for _, test := range tests {
+		start, end, err := parseRange(test.s, test.count)
+		if (err != nil) != test.err || (err == nil && (start != test.start || end != test.end)) {
+			t.Errorf("parseRange(%q, %d) = %d, %d, %v, want %d, %d, error %t", test.s, test.count, start, end, err, test.start, test.end, test.err)
+		}
+	}
+}
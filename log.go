@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+)
+
+var logLevelFlag = flag.String("log-level", "info", "Level of the structured logs written to stderr, one of: debug, info, warn, error")
+var quietFlag = flag.Bool("quiet", false, "Only log errors to stderr, overriding -log-level, so only the results are output")
+
+// Structured logs go to stderr so stdout stays clean for results
+func setupLogging() error {
+	level := slog.LevelInfo
+	if err := level.UnmarshalText([]byte(*logLevelFlag)); err != nil {
+		return err
+	}
+	// Fatal errors are still logged
+	if *quietFlag {
+		level = slog.LevelError
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+	return nil
+}
+
+// Logs an error and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
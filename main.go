@@ -1,27 +1,214 @@
 package main
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"net/url"
+	"io"
+	"log/slog"
 	"os"
+	"runtime"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
-var usagePrefix = fmt.Sprintf(`Reads a .har file from stdin, prints all request parameters that are reflected in the response body to stdout
+var usagePrefix = fmt.Sprintf(`Reads .har files, or stdin if there are none, prints all request parameters that are reflected in the response body to stdout
 
-Usage: %s [OPTIONS]
+Usage: %s [OPTIONS] [FILE...]
 
 OPTIONS:
 `, os.Args[0])
 
-var domainsFlag = flag.String("domains", "", "Filter by space delimited list of domains")
+var inputFormatFlag = flag.String("input-format", "har", "Input format, one of: har, jsonl (one HAR entry object per line, same as -entries-ndjson), mitmproxy (a flow dump from mitmdump -w)")
+var baseURLFlag = flag.String("base-url", "", "Resolve relative request URLs against this when they have no Referer, e.g. https://example.com/")
+var domainsFlag = flag.String("domains", "", "Filter by space delimited list of domains, a domain with a port only matches that port e.g. example.com:8080 or [::1]:8080")
+var contentTypesFlag = flag.String("content-types", "text/html application/xhtml+xml image/svg+xml", "Only scan responses with one of these space delimited mime types e.g. 'text/html text/javascript application/javascript text/css', wildcards like text/* work and empty scans all. JSON responses are scanned either way, for values that reflect JSON string escaped")
+var formatFlag = flag.String("format", "json", "Output format, one of: json, ndjson, pairs, burp, csv, line. Several comma separated formats are written to as many comma separated -output-file")
+var includeRequestBodyFlag = flag.Bool("include-request-body", false, "Include the raw query string and request body in each result, to reproduce findings without the HAR. Sensitive values are masked with -redact")
+var flatFlag = flag.Bool("flat", false, "With -format json or ndjson, output one flat list of findings that each have their method, url and source instead of a result per entry")
+var outputFileFlag = flag.String("output-file", "", "Write output to this file instead of stdout, or comma separated files for each -format")
+var mergeOutputFlag = flag.Bool("merge-output", false, "Add to the results already in -output-file instead of overwriting it, for -format json or ndjson. Not safe for runs merging at the same time")
+var valuesOnlyFlag = flag.Bool("values-only", false, "Only output the distinct reflected values, one per line in the order they're found, e.g. to feed payload generation")
+var countOnlyFlag = flag.Bool("count-only", false, "Only output the number of findings, logging a count per context")
+var sortFlag = flag.String("sort", "", "Sort output by one of: url, param, severity (most severe first), matches (most first), instead of entry order. Output isn't streamed when sorting")
+var reverseFlag = flag.Bool("reverse", false, "Reverse the -sort order")
+var minReflectionsFlag = flag.Int("min-reflections", 1, "With -format pairs, only report pairs that reflect in at least this many entries")
+var matchRegexFlag = flag.String("match-regex", "", "Count a value as reflected only if this regex matches the response, with {{value}} replaced by the quoted value e.g. '<b>{{value}}</b>'")
+var attrWhitespaceFlag = flag.Bool("attr-whitespace", false, "Also match values in HTML attribute values whatever the whitespace around and between their words, e.g. 'foo bar' as ' foo  bar', marking them whitespace")
+var stripTagsFlag = flag.Bool("strip-tags", false, "Also match values split by HTML tags, like search terms the page highlights, marking them fuzzy")
+var gapToleranceFlag = flag.Int("gap-tolerance", 0, "Also match values that reflect with up to N characters dropped e.g. filtered angle brackets, 0 disables")
+var timeoutPerEntryFlag = flag.Duration("timeout-per-entry", 0, "Abandon entries that take longer than this to analyze e.g. 5s, 0 disables")
+var entriesRangeFlag = flag.String("entries-range", "", "Only scan entries start:end (zero based, end exclusive) e.g. 10:20")
+var noJSONArraysFlag = flag.Bool("no-json-arrays", false, "Don't search the elements of values that are json arrays, only the array as a whole")
+var maxArrayElementsFlag = flag.Int("max-array-elements", 0, "Only search the first N elements of values that are json arrays, for data heavy APIs, 0 searches all")
+var minB64LenFlag = flag.Int("min-b64-len", 0, "Only try base64 decoding values at least this long")
+var decodeBase64BodyFlag = flag.Bool("decode-base64-body", false, "Also match against the decoded body of responses that are entirely base64 text, like apps that ship state as one base64 blob")
+var bodyBase64Flag = flag.Bool("body-base64", false, "Also search base64 segments of response bodies that decode to text, like encoded JSON, not just base64 data: URIs")
+var b64AlphabetFlag = flag.String("b64-alphabet", "", "Also try base64 decoding values with this custom 64 character alphabet")
+var fuzzyDistanceFlag = flag.Int("fuzzy-distance", 0, "Also match values that reflect within this Levenshtein distance, expensive so 0 disables")
+var fuzzyMinLenFlag = flag.Int("fuzzy-min-len", 8, "Only fuzzy match values at least this long, see -fuzzy-distance")
+var includeSkippedFlag = flag.Bool("include-skipped", false, "Also output entries that were filtered out or failed, with the reason they were skipped")
+var workersFlag = flag.Int("workers", runtime.NumCPU(), "Number of entries to scan concurrently, output order doesn't depend on it")
+var stripBOMFlag = flag.Bool("strip-bom", true, "Remove UTF-8 byte order marks from response bodies so offsets and contexts aren't thrown off")
+var checkpointFlag = flag.String("checkpoint", "", "Resume scanning stdin after the entry recorded in this file and keep it updated, best with -format ndjson which is written as entries finish")
+var entriesNDJSONFlag = flag.Bool("entries-ndjson", false, "Read one HAR entry object per line instead of a whole HAR, scanning each as it arrives")
+var strictFlag = flag.Bool("strict", false, "Fail on malformed lines of -entries-ndjson input instead of skipping them with a warning, and on findings that couldn't be posted to -sink-url")
+var unescapeBodyFlag = flag.Bool("unescape-body", false, "HTML unescape response bodies before matching, for captures whose content.text was escaped by the capture tool. This hides reflections the server really escaped, so only use it for such captures")
+var firstMatchOnlyFlag = flag.Bool("first-match-only", false, "Report only the first match of each value instead of every one, to keep output small and stop searching large bodies early")
+var validateFlag = flag.Bool("validate", false, "Print warnings to stderr about the HAR version and which tool created it")
+var envelopeFlag = flag.Bool("envelope", false, "Wrap json output in {meta: {...}, results: [...]} with the title, time, version and flags of the scan")
+var reportTitleFlag = flag.String("report-title", "", "Title for the -envelope metadata")
+var redactArgsFlag = flag.Bool("redact-args", false, "Redact flag values in the -envelope metadata")
+var maxResultsPerEntryFlag = flag.Int("max-results-per-entry", 0, "Only output the N most severe findings of each entry, marking the result truncated")
+var truncateValueFlag = flag.Int("truncate-value", 0, "Shorten output values to N characters and escape non printable ones, matching still uses the full value")
+var serveFlag = flag.String("serve", "", "Instead of reading stdin, listen on this address e.g. :8080 and respond to each POSTed .har file with its results")
+var serveMaxBytesFlag = flag.Int64("serve-max-bytes", 256<<20, "With -serve, reject .har files larger than this many bytes, and match bodies that decompress to more as stored")
+var redactFlag = flag.Bool("redact", false, "Mask the values of params matching -redact-params in the output")
+var redactParamsFlag = flag.String("redact-params", "*token* *password* *passwd* *secret* *session* *auth* *key* *csrf*", "Space delimited, case insensitive glob patterns of param names to -redact, matched against every element of the key")
+var skipStaticFlag = flag.Bool("skip-static", false, "Skip requests for static assets, by -static-extensions and -static-mime-types")
+var staticExtensionsFlag = flag.String("static-extensions", ".js .mjs .css .map .png .jpg .jpeg .gif .webp .ico .bmp .woff .woff2 .ttf .otf .eot .mp4 .webm .mp3", "Space delimited, case insensitive URL path extensions of static assets for -skip-static")
+var staticMimeTypesFlag = flag.String("static-mime-types", "text/css text/javascript application/javascript font/* image/png image/jpeg image/gif image/webp image/x-icon video/* audio/*", "Space delimited response mime types of static assets for -skip-static, wildcards like font/* work")
+var skipCachedFlag = flag.Bool("skip-cached", false, "Skip entries served from cache or whose body wasn't captured instead of marking their results fromCache or bodyMissing")
+var sstiFlag = flag.Bool("ssti", false, "Also report values with template expressions like {{7*7}} whose result reflects, for server side template injection")
+var noBodyMatchFlag = flag.Bool("no-body-match", false, "Output every value extracted from scanned requests, matching those that don't reflect at the start of the body and marking them forced, to debug extraction, decoding and context")
+var explainFlag = flag.Bool("explain", false, "Log why each entry was filtered out and why each value was or wasn't a finding, for tuning filters")
+var byValueFlag = flag.Bool("by-value", false, "Instead of results per entry, output each distinct value with every request that sent it and every response in the capture it reflects in, across all files given")
+var userAgentContainsFlag = flag.String("user-agent-contains", "", "Only scan entries whose request User-Agent contains this, for captures mixing several browsers or crawlers")
+var templateFlag = flag.String("template", "", "Go text/template evaluated per result instead of json output e.g. '{{.Method}} {{.URL}} {{range .XSS}}{{.Path}} {{end}}'")
+
+// Repeatable flags
+var headerFilters stringsFlag
+var headerMatches stringsFlag
+var responseHeaderFilters stringsFlag
+
+func init() {
+	flag.Var(&headerFilters, "header-filter", "Only scan entries with a request header matching 'Name: value-substring', or just 'Name' to require the header, can be repeated and all must match")
+	flag.Var(&headerMatches, "header-match", "Only scan entries with a request header of exactly 'Name: value' e.g. 'X-Requested-With: XMLHttpRequest', or just 'Name' to require the header, can be repeated and all must match")
+	flag.Var(&responseHeaderFilters, "response-header-filter", "Only scan entries with a response header matching 'Name: value-substring', 'Name' to require the header or '!Name' to require its absence e.g. '!Content-Security-Policy', can be repeated and all must match")
+	flag.BoolVar(firstMatchOnlyFlag, "first-hit-only", false, "Same as -first-match-only")
+}
 
 type KeyValue struct {
 	Key   []string `json:"key"` // Keys can be nested e.g. person.parent.name
 	Value string   `json:"value"`
+
+	// Where the value came from in the HAR e.g.
+	// log.entries[12].request.queryString[3]
+	HarPath string `json:"harPath"`
+	// With -follow-redirects, URL of the earlier request the value is from
+	// when it reflects after a redirect to this one
+	RedirectedFrom string `json:"redirectedFrom,omitempty"`
+
+	// Every place the value reflects, just the first with -first-match-only
+	Matches []Match `json:"matches,omitempty"`
+
+	// How exploitable the reflection looks from 0 to 10, see README.md
+	Severity int `json:"severity"`
+
+	// One of exact, encoded, partial, heuristic and none, see confidence
+	Confidence string `json:"confidence,omitempty"`
+
+	// Doesn't reflect, matched anyway by -no-body-match
+	Forced bool `json:"forced,omitempty"`
+
+	// Reflects in what the body decodes to rather than the body itself, with
+	// -decode-base64-body, and Matches are offsets in the decoded body
+	DecodedBody bool `json:"decodedBody,omitempty"`
+
+	// "ssti" when template expressions in the value reflect evaluated, as
+	// Evaluated, with -ssti. Empty for plain reflections.
+	Finding   string `json:"finding,omitempty"`
+	Evaluated string `json:"evaluated,omitempty"`
+
+	// Characters the response dropped from the value, see -gap-tolerance
+	Dropped string `json:"dropped,omitempty"`
+
+	// Edit distance of the reflection, see -fuzzy-distance
+	Distance int `json:"distance,omitempty"`
+
+	// Reflects split by HTML tags, see -strip-tags
+	Fuzzy bool `json:"fuzzy,omitempty"`
+
+	// Unicode normalization form the value reflects after, see -normalize,
+	// and what it reflects as
+	Normalization string `json:"normalization,omitempty"`
+
+	// Reflects in an attribute value with other whitespace, see
+	// -attr-whitespace
+	Whitespace bool `json:"whitespace,omitempty"`
+
+	// Every form the value reflects in, see reflectionForms
+	Forms []string `json:"forms,omitempty"`
+
+	// How the value was escaped when it reflects, e.g. html, and the
+	// escaped form found in the response
+	Escaping string `json:"escaping,omitempty"`
+	Escaped  string `json:"escaped,omitempty"`
+
+	// The value is most of the response, the most dangerous kind of reflection
+	FullReflection bool `json:"fullReflection,omitempty"`
+
+	// Where the value reflects in an HTML response e.g. attribute, script
+	Context string `json:"context,omitempty"`
+
+	// The element the value reflects in when it's title or meta
+	Element string `json:"element,omitempty"`
+
+	// Element path the value reflects in with -xml e.g.
+	// Envelope.Body.GetUserResponse.name
+	XMLPath string `json:"xmlPath,omitempty"`
+
+	// Field the value reflects in when Context is json-script
+	JSONPath string `json:"jsonPath,omitempty"`
+
+	// Whether the value starts the URL when Context is url-attribute, so it
+	// controls the scheme
+	URLStart bool `json:"urlStart,omitempty"`
+	// Scheme of the URL the value starts when it's data, blob or javascript
+	URLScheme string `json:"urlScheme,omitempty"`
+}
+
+// Dot delimited key e.g. query.person.name
+func (kv *KeyValue) Path() string {
+	return strings.Join(kv.Key, ".")
+}
+
+type Result struct {
+	File     string      `json:"file,omitempty"` // When reading files instead of stdin
+	Method   string      `json:"method"`
+	URL      string      `json:"url"`
+	MimeType string      `json:"mimeType,omitempty"` // Of the response
+	Status   int         `json:"status,omitempty"`   // Of the response
+	BodySize int         `json:"bodySize,omitempty"` // Of the decoded response body
+	XSS      []*KeyValue `json:"xss"`
+
+	// Bytes of the request and response bodies as sent, from the HAR or else
+	// the post data and decoded body
+	RequestBodySize  int `json:"requestBodySize,omitempty"`
+	ResponseBodySize int `json:"responseBodySize,omitempty"`
+
+	// With -include-request-body
+	Request *RawRequest `json:"request,omitempty"`
+
+	// Findings were dropped by -max-results-per-entry
+	Truncated bool `json:"truncated,omitempty"`
+
+	// Content-Security-Policy header of the response, severity is lowered
+	// when it is strict
+	CSP string `json:"csp,omitempty"`
+
+	// The body wasn't checked for reflections, or is the browser's cached
+	// copy, so an empty XSS doesn't mean no reflection
+	FromCache   bool `json:"fromCache,omitempty"`
+	BodyMissing bool `json:"bodyMissing,omitempty"`
+
+	// Why the entry wasn't scanned, see -include-skipped
+	Skipped string `json:"skipped,omitempty"`
 }
 
 func main() {
@@ -31,116 +218,311 @@ func main() {
 		flag.PrintDefaults()
 	}
 	flag.Parse()
+	if err := setupLogging(); err != nil {
+		fatal("Invalid flags", "err", err)
+	}
+	if err := validateMatchRegex(); err != nil {
+		fatal("Invalid flags", "err", err)
+	}
+	if err := setupBase64(); err != nil {
+		fatal("Invalid flags", "err", err)
+	}
+	if err := setupFindingFilters(); err != nil {
+		fatal("Invalid flags", "err", err)
+	}
+	if err := setupParamValueRegex(); err != nil {
+		fatal("Invalid flags", "err", err)
+	}
+	if err := setupNormalize(); err != nil {
+		fatal("Invalid flags", "err", err)
+	}
+	if err := setupSink(); err != nil {
+		fatal("Invalid flags", "err", err)
+	}
+	setupWorkers()
+	if err := startPprof(); err != nil {
+		fatal("Starting pprof", "err", err)
+	}
 
-	// Parse the .har file
-	har := struct {
-		Log struct {
-			Entries []struct {
-				Request struct {
-					Method      string `json:"method"`
-					URL         string `json:"url"`
-					QueryString []struct {
-						Name  string `json:"name"`
-						Value string `json:"value"`
-					} `json:"queryString"`
-					PostData struct {
-						Params []struct {
-							Name  string `json:"name"`
-							Value string `json:"value"`
-						} `json:"params"`
-						Text string `json:"text"`
-					} `json:"postData"`
-				} `json:"request"`
-				Response struct {
-					Content struct {
-						Text string `json:"text"`
-					} `json:"content"`
-				} `json:"response"`
-			} `json:"entries"`
-		} `json:"log"`
-	}{}
-	if err := json.NewDecoder(os.Stdin).Decode(&har); err != nil {
-		panic(err)
-	}
-
-	domains := strings.Fields(*domainsFlag)
-	results := []interface{}{}
-	for _, entry := range har.Log.Entries {
-		keyValueChan := make(chan *KeyValue)
-		go func() {
-			defer close(keyValueChan)
-
-			// Search query params
-			for _, queryString := range entry.Request.QueryString {
-				for keyValue := range search(
-					[]string{"query", queryString.Name},
-					queryString.Value,
-				) {
-					keyValueChan <- keyValue
-				}
+	if *serveFlag != "" {
+		write, err := resultWriter(*formatFlag)
+		if err != nil {
+			fatal("Invalid flags", "err", err)
+		}
+		if err := serve(*serveFlag, write); err != nil {
+			fatal("Serving", "err", err)
+		}
+		return
+	}
+	out, write, err := openOutput()
+	if err != nil {
+		fatal("Invalid flags", "err", err)
+	}
+
+	if *byValueFlag {
+		if err := writeByValue(flag.Args(), out); err != nil {
+			fatal("Scanning HAR", "err", err)
+		}
+		return
+	}
+	if *canaryFlag != "" {
+		if err := writeCanary(flag.Args(), out); err != nil {
+			fatal("Scanning HAR", "err", err)
+		}
+		return
+	}
+	if flag.NArg() == 0 {
+		if err := scanStdin(write, out); err != nil {
+			fatal("Scanning HAR", "err", err)
+		}
+		if err := closeSink(); err != nil {
+			fatal("Scanning HAR", "err", err)
+		}
+		return
+	}
+	results, ok := scanFiles(flag.Args())
+	if err := closeSink(); err != nil {
+		fatal("Scanning HAR", "err", err)
+	}
+	if err := write(out, results); err != nil {
+		fatal("Writing results", "err", err)
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// Scans stdin, resuming from and updating the -checkpoint. Ndjson is written
+// as entries finish so the checkpoint can advance with it, other formats only
+// once everything is scanned.
+func scanStdin(write func(io.Writer, []*Result) error, out io.Writer) error {
+	cp, err := loadCheckpoint(*checkpointFlag)
+	if err != nil {
+		return err
+	}
+	stream := *formatFlag == "ndjson" && *templateFlag == "" && *sortFlag == "" && !*countOnlyFlag && !*valuesOnlyFlag && !*statsJSONFlag
+	results := []*Result{}
+	end := 0
+	var writeErr error
+	err = scanHAR(context.Background(), os.Stdin, cp.Next(), func(i int, result *Result) {
+		end = i + 1
+		if writeErr != nil {
+			return
+		}
+		if !stream {
+			if result != nil {
+				results = append(results, result)
 			}
+			return
+		}
+		if result != nil {
+			writeErr = writeNDJSON(out, []*Result{result})
+		}
+		if writeErr == nil {
+			writeErr = cp.Advance(end)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	if !stream {
+		if err := write(out, results); err != nil {
+			return err
+		}
+	}
+	return cp.Save(max(end, cp.Next()))
+}
 
-			// Search post params
-			for _, param := range entry.Request.PostData.Params {
-				for keyValue := range search(
-					[]string{"form", param.Name},
-					param.Value,
-				) {
-					keyValueChan <- keyValue
-				}
+// Scans -workers files at a time, their entries sharing the -workers
+// entrySlots. Results are in argument order then entry order. A file that
+// fails is logged and skipped, false if any did.
+func scanFiles(paths []string) ([]*Result, bool) {
+	indexed := make([][]*Result, len(paths))
+	failed := make([]bool, len(paths))
+	sem := make(chan struct{}, max(*workersFlag, 1))
+	wg := sync.WaitGroup{}
+	for i, path := range paths {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, path string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results, err := scanFile(path)
+			if err != nil {
+				slog.Error("Scanning HAR", "file", path, "err", err)
+				failed[i] = true
+				return
 			}
+			indexed[i] = results
+		}(i, path)
+	}
+	wg.Wait()
+
+	results := []*Result{}
+	ok := true
+	for i := range paths {
+		results = append(results, indexed[i]...)
+		ok = ok && !failed[i]
+	}
+	return results, ok
+}
+
+func scanFile(path string) ([]*Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	results, err := collectHAR(context.Background(), f)
+	for _, result := range results {
+		result.File = path
+	}
+	return results, err
+}
+
+// Scans a whole .har file into a slice
+func collectHAR(ctx context.Context, r io.Reader) ([]*Result, error) {
+	results := []*Result{}
+	err := scanHAR(ctx, r, 0, func(i int, result *Result) {
+		if result != nil {
+			results = append(results, result)
+		}
+	})
+	return results, err
+}
+
+// Parses a .har file and scans it from entry index resume on, the same for
+// the CLI and -serve. Emit is called for each entry in order, with a nil
+// result if it's filtered out of the output. Reading stops once ctx is done,
+// and entries being scanned are abandoned.
+func scanHAR(ctx context.Context, r io.Reader, resume int, emit func(i int, result *Result)) error {
+	runStart := time.Now()
+	entries := make(chan indexedEntry)
+	errChan := make(chan error, 1)
+	go func() {
+		defer close(entries)
+		errChan <- readEntries(ctx, r, resume, entries)
+	}()
 
-			// Search body
-			for keyValue := range search([]string{"body"}, entry.Request.PostData.Text) {
-				keyValueChan <- keyValue
+	scanned, results := 0, 0
+	scan(ctx, entries, func(i int, result *Result) {
+		scanned++
+		if result != nil {
+			results++
+			if findingSink != nil {
+				findingSink.send(result)
 			}
-		}()
-		if 0 < len(domains) {
-			u, err := url.Parse(entry.Request.URL)
-			if err != nil {
-				panic(err)
+		}
+		emit(i, result)
+	})
+	if err := <-errChan; err != nil {
+		return err
+	}
+	slog.Info("Scanned HAR", "entries", scanned, "results", results, "duration", time.Since(runStart))
+	return nil
+}
+
+// All the key values of a request
+func searchRequest(ctx context.Context, request *Request) <-chan *KeyValue {
+	keyValueChan := make(chan *KeyValue)
+	go func() {
+		defer close(keyValueChan)
+
+		// Search query params
+		queryKeys := paramKeys("query", request.QueryString)
+		for j, queryString := range request.QueryString {
+			for keyValue := range search(
+				ctx,
+				queryKeys[j],
+				queryString.Value,
+			) {
+				keyValue.HarPath = fmt.Sprintf("request.queryString[%d]", j)
+				if !send(ctx, keyValueChan, keyValue) {
+					return
+				}
 			}
-			ok := false
-			for _, domain := range domains {
-				if domain == u.Host {
-					ok = true
-					break
+		}
+
+		// Search post params
+		formKeys := paramKeys("form", request.PostData.Params)
+		for j, param := range request.PostData.Params {
+			// The name of an uploaded file is user controlled and often
+			// reflected, its contents only matter if they're text
+			if param.FileName != "" {
+				for keyValue := range search(ctx, appendKey(formKeys[j], "fileName"), param.FileName) {
+					keyValue.HarPath = fmt.Sprintf("request.postData.params[%d].fileName", j)
+					if !send(ctx, keyValueChan, keyValue) {
+						return
+					}
+				}
+				if !isTextContentType(param.ContentType) {
+					continue
 				}
 			}
-			if !ok {
-				continue
+			for keyValue := range search(
+				ctx,
+				formKeys[j],
+				param.Value,
+			) {
+				keyValue.HarPath = fmt.Sprintf("request.postData.params[%d]", j)
+				if !send(ctx, keyValueChan, keyValue) {
+					return
+				}
 			}
 		}
-		respBody, err := base64.StdEncoding.DecodeString(entry.Response.Content.Text)
-		if err != nil {
-			panic(err)
-		}
-		respBodyString := string(respBody)
 
-		keyValues := []*KeyValue{}
-		for keyValue := range keyValueChan {
-			// TODO: Filter content type
-			if strings.Contains(respBodyString, keyValue.Value) {
-				keyValues = append(keyValues, keyValue)
+		// Search graphql operations, or else the body as a whole
+		if operations, ok := parseGraphQL(&request.PostData); ok {
+			for keyValue := range searchGraphQL(ctx, operations) {
+				keyValue.HarPath = "request.postData.text"
+				if !send(ctx, keyValueChan, keyValue) {
+					return
+				}
 			}
+			return
 		}
-		results = append(results, struct {
-			Method string      `json:"method"`
-			URL    string      `json:"url"`
-			XSS    []*KeyValue `json:"xss"`
-		}{
-			Method: entry.Request.Method,
-			URL:    entry.Request.URL,
-			XSS:    keyValues,
-		})
+		for keyValue := range search(ctx, []string{"body"}, request.PostData.Text) {
+			keyValue.HarPath = "request.postData.text"
+			if !send(ctx, keyValueChan, keyValue) {
+				return
+			}
+		}
+	}()
+	return keyValueChan
+}
+
+// Whether an uploaded file of this content type is text worth searching
+func isTextContentType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return strings.HasPrefix(contentType, "text/") || strings.Contains(contentType, "json") || strings.Contains(contentType, "xml")
+}
+
+// Keys of params e.g. ["query", "a"], names that repeat are indexed by
+// occurrence like json arrays e.g. ["query", "a", "0"] and ["query", "a", "1"]
+func paramKeys(prefix string, params []Param) [][]string {
+	counts := map[string]int{}
+	for _, param := range params {
+		counts[param.Name]++
 	}
-	if err := json.NewEncoder(os.Stdout).Encode(results); err != nil {
-		panic(err)
+	seen := map[string]int{}
+	keys := make([][]string, len(params))
+	for i, param := range params {
+		keys[i] = []string{prefix, param.Name}
+		if 1 < counts[param.Name] {
+			keys[i] = append(keys[i], fmt.Sprintf("%d", seen[param.Name]))
+			seen[param.Name]++
+		}
 	}
+	return keys
 }
 
 // Recursive key value search
-func search(key []string, value string) <-chan *KeyValue {
+func search(ctx context.Context, key []string, value string) <-chan *KeyValue {
 	keyValueChan := make(chan *KeyValue)
 	go func() {
 		defer close(keyValueChan)
@@ -149,19 +531,34 @@ func search(key []string, value string) <-chan *KeyValue {
 		// Maybe a json map
 		valueMap := map[string]json.RawMessage{}
 		if err := json.Unmarshal(valueBytes, &valueMap); err == nil {
-			for key2, value2 := range valueMap {
-				for keyValue := range search(append(key, key2), string(value2)) {
-					keyValueChan <- keyValue
+			// Sorted so output is deterministic
+			keys := make([]string, 0, len(valueMap))
+			for key2 := range valueMap {
+				keys = append(keys, key2)
+			}
+			sort.Strings(keys)
+			for _, key2 := range keys {
+				value2 := valueMap[key2]
+				for keyValue := range search(ctx, appendKey(key, key2), string(value2)) {
+					if !send(ctx, keyValueChan, keyValue) {
+						return
+					}
 				}
 			}
 		}
 
-		// Maybe a json list
+		// Maybe a json list, big ones can be a lot of values
 		valueList := []json.RawMessage{}
-		if err := json.Unmarshal(valueBytes, &valueList); err == nil {
+		if err := json.Unmarshal(valueBytes, &valueList); err == nil && !*noJSONArraysFlag {
+			if 0 < *maxArrayElementsFlag && *maxArrayElementsFlag < len(valueList) {
+				slog.Debug("Searching only -max-array-elements", "key", strings.Join(key, "."), "elements", len(valueList))
+				valueList = valueList[:*maxArrayElementsFlag]
+			}
 			for key2, value2 := range valueList {
-				for keyValue := range search(append(key, fmt.Sprintf("%d", key2)), string(value2)) {
-					keyValueChan <- keyValue
+				for keyValue := range search(ctx, appendKey(key, fmt.Sprintf("%d", key2)), string(value2)) {
+					if !send(ctx, keyValueChan, keyValue) {
+						return
+					}
 				}
 			}
 		}
@@ -169,29 +566,91 @@ func search(key []string, value string) <-chan *KeyValue {
 		// Maybe a json string
 		valueString := ""
 		if err := json.Unmarshal(valueBytes, &valueString); err == nil {
-			for keyValue := range search(key, valueString) {
-				keyValueChan <- keyValue
+			for keyValue := range search(ctx, key, valueString) {
+				if !send(ctx, keyValueChan, keyValue) {
+					return
+				}
 			}
 		}
 
-		// Maybe base64 encoded
-		if bytes, _ := base64.StdEncoding.DecodeString(value); 0 < len(bytes) {
+		// Maybe base64 encoded, short strings can decode by coincidence
+		for _, decoded := range decodeBase64(value) {
 			// TODO: Maybe check this
 			// isPrint
-			// for _, r := range string(bytes) {
+			// for _, r := range decoded {
 			// 	if !unicode.IsPrint(r) {
 			// 		return
 			// 	}
 			// }
-			for keyValue := range search(key, string(bytes)) {
-				keyValueChan <- keyValue
+			for keyValue := range search(ctx, key, decoded) {
+				if !send(ctx, keyValueChan, keyValue) {
+					return
+				}
 			}
 		}
 
-		keyValueChan <- &KeyValue{
+		send(ctx, keyValueChan, &KeyValue{
 			Key:   key,
 			Value: value,
-		}
+		})
 	}()
 	return keyValueChan
 }
+
+// Appends to a copy of key, so the keys of sibling values never share memory
+func appendKey(key []string, elems ...string) []string {
+	return append(append(make([]string, 0, len(key)+len(elems)), key...), elems...)
+}
+
+// Set from -b64-alphabet
+var customBase64Encodings []*base64.Encoding
+
+// Builds padded and unpadded encodings from -b64-alphabet
+func setupBase64() error {
+	alphabet := *b64AlphabetFlag
+	if alphabet == "" {
+		return nil
+	}
+	if len(alphabet) != 64 {
+		return fmt.Errorf("-b64-alphabet must be 64 characters, got %d", len(alphabet))
+	}
+	for i := 0; i < len(alphabet); i++ {
+		if c := alphabet[i]; c == '\r' || c == '\n' || c == '=' || strings.IndexByte(alphabet[i+1:], c) != -1 {
+			return fmt.Errorf("-b64-alphabet has an invalid or repeated character %q", c)
+		}
+	}
+	encoding := base64.NewEncoding(alphabet)
+	customBase64Encodings = []*base64.Encoding{encoding, encoding.WithPadding(base64.NoPadding)}
+	return nil
+}
+
+// Distinct decodings of values at least -min-b64-len long, with the standard
+// alphabet and -b64-alphabet
+func decodeBase64(value string) []string {
+	if len(value) < *minB64LenFlag {
+		return nil
+	}
+	decodings := []string{}
+	if bytes, _ := base64.StdEncoding.DecodeString(value); 0 < len(bytes) {
+		decodings = append(decodings, string(bytes))
+	}
+	// Only complete decodes, the unpadded encoding partially decodes padded
+	// values
+	for _, encoding := range customBase64Encodings {
+		if bytes, err := encoding.DecodeString(value); err == nil && 0 < len(bytes) && !slices.Contains(decodings, string(bytes)) {
+			decodings = append(decodings, string(bytes))
+		}
+	}
+	return decodings
+}
+
+// Sends unless the context is done first, in which case the consumer has
+// stopped reading and the producer should return
+func send(ctx context.Context, keyValueChan chan<- *KeyValue, keyValue *KeyValue) bool {
+	select {
+	case keyValueChan <- keyValue:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParamKeys(t *testing.T) {
+	params := []Param{{Name: "a"}, {Name: "b"}, {Name: "a"}, {Name: "a"}}
+	want := [][]string{
+		{"query", "a", "0"},
+		{"query", "b"},
+		{"query", "a", "1"},
+		{"query", "a", "2"},
+	}
+	if got := paramKeys("query", params); !reflect.DeepEqual(got, want) {
+		t.Errorf("paramKeys = %v, want %v", got, want)
+	}
+	if got := paramKeys("form", nil); len(got) != 0 {
+		t.Errorf("paramKeys of no params = %v, want none", got)
+	}
+}
@@ -0,0 +1,565 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Decoded response body, with the views of it matchers need computed at most
+// once per entry
+type responseBody struct {
+	text string
+
+	unescapeOnce     sync.Once
+	unescaped        string
+	unescapedOffsets []int
+
+	dataURIsOnce sync.Once
+	dataURIs     []dataURI
+
+	base64SegmentsOnce sync.Once
+	base64Segments     []dataURI
+
+	stripTagsOnce   sync.Once
+	stripped        string
+	strippedOffsets []int
+
+	isXML        bool // With -xml
+	xmlNodesOnce sync.Once
+	nodes        []xmlNode
+
+	normalizeOnce sync.Once
+	normalized    map[string]normalizedText // By -normalize form
+}
+
+// Decoded payload of a base64 data: URI in a body
+type dataURI struct {
+	offset  int // Where the payload starts
+	payload string
+}
+
+var base64DataURIRegexp = regexp.MustCompile(`(?i)data:[^,"'\s]*;base64,([a-z0-9+/]+=*)`)
+
+// The base64 data: URIs in the body, decoded
+func (b *responseBody) base64DataURIs() []dataURI {
+	b.dataURIsOnce.Do(func() {
+		for _, loc := range base64DataURIRegexp.FindAllStringSubmatchIndex(b.text, -1) {
+			if payload, err := base64.StdEncoding.DecodeString(b.text[loc[2]:loc[3]]); err == nil {
+				b.dataURIs = append(b.dataURIs, dataURI{
+					offset:  loc[2],
+					payload: string(payload),
+				})
+			}
+		}
+	})
+	return b.dataURIs
+}
+
+// Runs of base64 in a body long enough to be worth decoding
+var base64SegmentRegexp = regexp.MustCompile(`[A-Za-z0-9+/]{16,}={0,2}`)
+
+// Share of printable runes a decoded segment needs, most runs of base64
+// characters in a body aren't base64 and decode to junk
+const minPrintableRatio = 0.9
+
+// The -body-base64 segments of the body at least -min-b64-len long that
+// decode to mostly printable text, decoded
+func (b *responseBody) base64DecodedSegments() []dataURI {
+	b.base64SegmentsOnce.Do(func() {
+		for _, loc := range base64SegmentRegexp.FindAllStringIndex(b.text, -1) {
+			segment := b.text[loc[0]:loc[1]]
+			if len(segment) < *minB64LenFlag {
+				continue
+			}
+			if payload, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(segment, "=")); err == nil && isMostlyPrintable(string(payload)) {
+				b.base64Segments = append(b.base64Segments, dataURI{
+					offset:  loc[0],
+					payload: string(payload),
+				})
+			}
+		}
+	})
+	return b.base64Segments
+}
+
+func isMostlyPrintable(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	printable, total := 0, 0
+	for _, r := range s {
+		total++
+		if unicode.IsPrint(r) || unicode.IsSpace(r) {
+			printable++
+		}
+	}
+	return 0 < total && minPrintableRatio <= float64(printable)/float64(total)
+}
+
+func newResponseBody(text string) *responseBody {
+	return &responseBody{text: text}
+}
+
+// The body HTML unescaped, and for each of its bytes the offset in text it
+// came from
+func (b *responseBody) htmlUnescaped() (string, []int) {
+	b.unescapeOnce.Do(func() {
+		b.unescaped, b.unescapedOffsets = unescapeHTML(b.text)
+	})
+	return b.unescaped, b.unescapedOffsets
+}
+
+// The body without HTML tags, and for each of its bytes the offset in text
+// it came from
+func (b *responseBody) tagsStripped() (string, []int) {
+	b.stripTagsOnce.Do(func() {
+		b.stripped, b.strippedOffsets = stripTags(b.text)
+	})
+	return b.stripped, b.strippedOffsets
+}
+
+// Every form value reflects in, one of raw, url, html, json and base64,
+// where a match only reports the first one found
+func reflectionForms(b *responseBody, value string) []string {
+	forms := []string{}
+	if strings.Contains(b.text, value) {
+		forms = append(forms, "raw")
+	}
+	if escaped := url.QueryEscape(value); escaped != value && strings.Contains(b.text, escaped) {
+		forms = append(forms, "url")
+	} else if escaped := url.PathEscape(value); escaped != value && strings.Contains(b.text, escaped) {
+		forms = append(forms, "url")
+	}
+	if strings.ContainsAny(value, htmlSpecialChars) && b.reflectsHTMLEscaped(value) {
+		forms = append(forms, "html")
+	}
+	for _, escaped := range jsonEscapes(value) {
+		if strings.Contains(b.text, escaped) {
+			forms = append(forms, "json")
+			break
+		}
+	}
+	if b.reflectsBase64(value) {
+		forms = append(forms, "base64")
+	}
+	return forms
+}
+
+// Whether value is in the body with at least one character HTML escaped
+func (b *responseBody) reflectsHTMLEscaped(value string) bool {
+	unescaped, offsets := b.htmlUnescaped()
+	for from := 0; ; {
+		i := strings.Index(unescaped[from:], value)
+		if i == -1 {
+			return false
+		}
+		i += from
+		if b.text[offsets[i]:offsets[i+len(value)]] != value {
+			return true
+		}
+		from = i + 1
+	}
+}
+
+// Whether value is base64 encoded in the body, on its own or in a data: URI
+// or -body-base64 segment
+func (b *responseBody) reflectsBase64(value string) bool {
+	if strings.Contains(b.text, strings.TrimRight(base64.StdEncoding.EncodeToString([]byte(value)), "=")) {
+		return true
+	}
+	segments := b.base64DataURIs()
+	if *bodyBase64Flag {
+		segments = append(slices.Clip(segments), b.base64DecodedSegments()...)
+	}
+	for _, segment := range segments {
+		if strings.Contains(segment.payload, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether the value of keyValue reflects in body and at which offset,
+// annotating keyValue with how it reflected
+func match(b *responseBody, keyValue *KeyValue) (int, bool) {
+	body := b.text
+	// Empty values are trivially contained in every body
+	if keyValue.Value == "" {
+		return -1, false
+	}
+	if *matchRegexFlag != "" {
+		return matchRegex(body, keyValue.Value)
+	}
+	if i := strings.Index(body, keyValue.Value); i != -1 {
+		return i, true
+	}
+	// Values with characters HTML escapes may reflect escaped, typically
+	// inside attributes
+	if strings.ContainsAny(keyValue.Value, htmlSpecialChars) {
+		unescaped, offsets := b.htmlUnescaped()
+		if i := strings.Index(unescaped, keyValue.Value); i != -1 {
+			start, end := offsets[i], offsets[i+len(keyValue.Value)]
+			keyValue.Escaping, keyValue.Escaped = "html", body[start:end]
+			return start, true
+		}
+	}
+	// Values in JSON strings have quotes and backslashes escaped, and
+	// depending on the encoder < > & too
+	for _, escaped := range jsonEscapes(keyValue.Value) {
+		if i := strings.Index(body, escaped); i != -1 {
+			keyValue.Escaping, keyValue.Escaped = "json", escaped
+			return i, true
+		}
+	}
+	// Servers that normalize input change it byte wise, e.g. NFKC turns
+	// fullwidth brackets into plain ones
+	for _, form := range normalizeForms() {
+		value, _ := normalizers[form](keyValue.Value)
+		normalized := b.normalizedBodies()[form]
+		if i := strings.Index(normalized.text, value); value != "" && i != -1 {
+			start, end := normalized.offsets[i], normalized.offsets[i+len(value)]
+			keyValue.Normalization, keyValue.Escaped = form, body[start:max(end, start)]
+			return start, true
+		}
+	}
+	// Base64 data: URIs hide the value from a plain search
+	for _, uri := range b.base64DataURIs() {
+		if strings.Contains(uri.payload, keyValue.Value) {
+			keyValue.Escaping = "base64"
+			return uri.offset, true
+		}
+	}
+	if *bodyBase64Flag {
+		for _, segment := range b.base64DecodedSegments() {
+			if strings.Contains(segment.payload, keyValue.Value) {
+				keyValue.Escaping = "base64"
+				return segment.offset, true
+			}
+		}
+	}
+	if *attrWhitespaceFlag {
+		if i, ok := matchAttrWhitespace(body, keyValue.Value); ok {
+			keyValue.Whitespace = true
+			return i, true
+		}
+	}
+	if *stripTagsFlag {
+		if i, ok := matchTagsStripped(b, keyValue.Value); ok {
+			keyValue.Fuzzy = true
+			return i, true
+		}
+	}
+	if 0 < *gapToleranceFlag {
+		if i, dropped, ok := matchGaps(body, keyValue.Value, *gapToleranceFlag); ok {
+			keyValue.Dropped = dropped
+			return i, true
+		}
+	}
+	if 0 < *fuzzyDistanceFlag && *fuzzyMinLenFlag <= len(keyValue.Value) && len(keyValue.Value) <= maxFuzzyLen {
+		if i, distance, ok := matchFuzzy(body, keyValue.Value, *fuzzyDistanceFlag); ok {
+			keyValue.Distance = distance
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// Mime types of JSON responses, scanned on top of -content-types for values
+// that reflect JSON string escaped
+func isJSONMimeType(mimeType string) bool {
+	return mimeType == "application/json" || strings.HasSuffix(mimeType, "+json")
+}
+
+// The distinct ways JSON encoders write value inside a string that differ
+// from value itself, with and without HTML characters escaped
+func jsonEscapes(value string) []string {
+	escapes := []string{}
+	for _, escapeHTML := range []bool{true, false} {
+		buf := bytes.Buffer{}
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(escapeHTML)
+		if err := encoder.Encode(value); err != nil {
+			continue
+		}
+		// Without the quotes and newline Encode adds
+		escaped := strings.TrimSuffix(buf.String(), "\n")
+		escaped = escaped[1 : len(escaped)-1]
+		if escaped != value && !slices.Contains(escapes, escaped) {
+			escapes = append(escapes, escaped)
+		}
+	}
+	return escapes
+}
+
+// Where a value reflects in a response
+type Match struct {
+	Offset  int    `json:"offset"`  // In bytes into the decoded body
+	Line    int    `json:"line"`    // 1-based line of Offset
+	Column  int    `json:"column"`  // 1-based column of Offset in runes
+	Snippet string `json:"snippet"` // The reflection with some of the body around it
+}
+
+// Bytes of body on either side of a reflection in a Match snippet
+const snippetRadius = 32
+
+// Every reflection of an exact match starting at the first one found at
+// offset, or just that one with -first-match-only or when the match was
+// fuzzy, escaped etc. since there are no other exact occurrences
+func collectMatches(body string, offset int, keyValue *KeyValue) []Match {
+	isExact := keyValue.Escaping == "" && keyValue.Dropped == "" && keyValue.Distance == 0 && !keyValue.Fuzzy && !keyValue.Whitespace && keyValue.Normalization == "" && *matchRegexFlag == ""
+	matches := []Match{newMatch(body, offset, len(keyValue.Value))}
+	for isExact && !*firstMatchOnlyFlag {
+		i := strings.Index(body[offset+1:], keyValue.Value)
+		if i == -1 {
+			break
+		}
+		offset += 1 + i
+		matches = append(matches, newMatch(body, offset, len(keyValue.Value)))
+	}
+	return matches
+}
+
+func newMatch(body string, offset, length int) Match {
+	start, end := max(offset-snippetRadius, 0), min(offset+length+snippetRadius, len(body))
+	// Don't cut runes in half
+	for 0 < start && !utf8.RuneStart(body[start]) {
+		start--
+	}
+	for end < len(body) && !utf8.RuneStart(body[end]) {
+		end++
+	}
+	line, column := lineColumn(body, offset)
+	return Match{
+		Offset:  offset,
+		Line:    line,
+		Column:  column,
+		Snippet: body[start:end],
+	}
+}
+
+// 1-based line and column of offset in body, for opening it in an editor
+func lineColumn(body string, offset int) (int, int) {
+	lineStart := strings.LastIndexByte(body[:offset], '\n') + 1
+	return strings.Count(body[:lineStart], "\n") + 1, utf8.RuneCountInString(body[lineStart:offset]) + 1
+}
+
+// Placeholder in -match-regex for the quoted value
+const matchRegexValue = "{{value}}"
+
+// Compiles -match-regex for value
+func compileMatchRegex(value string) (*regexp.Regexp, error) {
+	return regexp.Compile(strings.ReplaceAll(*matchRegexFlag, matchRegexValue, regexp.QuoteMeta(value)))
+}
+
+// Checks -match-regex compiles before any scanning
+func validateMatchRegex() error {
+	if *matchRegexFlag == "" {
+		return nil
+	}
+	if !strings.Contains(*matchRegexFlag, matchRegexValue) {
+		return fmt.Errorf("-match-regex has no %s", matchRegexValue)
+	}
+	_, err := compileMatchRegex("")
+	return err
+}
+
+// Whether -match-regex with the value substituted in matches body
+func matchRegex(body, value string) (int, bool) {
+	re, err := compileMatchRegex(value)
+	if err != nil {
+		return -1, false
+	}
+	if loc := re.FindStringIndex(body); loc != nil {
+		// The value itself is where the context is
+		if i := strings.Index(body[loc[0]:loc[1]], value); i != -1 {
+			return loc[0] + i, true
+		}
+		return loc[0], true
+	}
+	return -1, false
+}
+
+// Characters servers HTML escape
+const htmlSpecialChars = `&<>"'`
+
+// HTML unescapes s, also returning for each byte of the result (and one past
+// the end) the offset in s it came from
+func unescapeHTML(s string) (string, []int) {
+	unescaped := strings.Builder{}
+	offsets := make([]int, 0, len(s)+1)
+	for i := 0; i < len(s); {
+		// Entities are short, this bounds the search for the ;
+		if s[i] == '&' {
+			if end := strings.IndexByte(s[i:min(i+32, len(s))], ';'); 0 < end {
+				entity := s[i : i+end+1]
+				if char := html.UnescapeString(entity); char != entity {
+					for j := 0; j < len(char); j++ {
+						offsets = append(offsets, i)
+					}
+					unescaped.WriteString(char)
+					i += len(entity)
+					continue
+				}
+			}
+		}
+		offsets = append(offsets, i)
+		unescaped.WriteByte(s[i])
+		i++
+	}
+	return unescaped.String(), append(offsets, len(s))
+}
+
+// Removes tags and comments from s, returning where each byte left came from
+func stripTags(s string) (string, []int) {
+	stripped := strings.Builder{}
+	offsets := make([]int, 0, len(s))
+	for i := 0; i < len(s); {
+		if s[i] == '<' && i+1 < len(s) && (isASCIILetter(s[i+1]) || s[i+1] == '/' || s[i+1] == '!') {
+			if end := strings.IndexByte(s[i:], '>'); end != -1 {
+				i += end + 1
+				continue
+			}
+		}
+		offsets = append(offsets, i)
+		stripped.WriteByte(s[i])
+		i++
+	}
+	return stripped.String(), offsets
+}
+
+// Most bytes of tags a reflection may be split by with -strip-tags, so a
+// value isn't pieced together from all over a page
+const maxStrippedTags = 256
+
+// Finds value in the body without tags, where it spans at least one tag since
+// it would match exactly otherwise
+func matchTagsStripped(b *responseBody, value string) (int, bool) {
+	stripped, offsets := b.tagsStripped()
+	for from := 0; ; {
+		i := strings.Index(stripped[from:], value)
+		if i == -1 {
+			return -1, false
+		}
+		i += from
+		start, end := offsets[i], offsets[i+len(value)-1]+1
+		if end-start <= len(value)+maxStrippedTags {
+			return start, true
+		}
+		from = i + 1
+	}
+}
+
+// Finds value in an attribute value with any whitespace around it and any
+// run of whitespace between its words, which browsers treat the same in
+// attributes like class
+func matchAttrWhitespace(body, value string) (int, bool) {
+	words := strings.FieldsFunc(value, func(r rune) bool {
+		return r < utf8.RuneSelf && isHTMLSpace(byte(r))
+	})
+	if len(words) == 0 {
+		return -1, false
+	}
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+	// Values decoded from base64 can be binary, which isn't a valid pattern
+	re, err := regexp.Compile(strings.Join(words, `[ \t\n\f\r]+`))
+	if err != nil {
+		return -1, false
+	}
+	for _, loc := range re.FindAllStringIndex(body, -1) {
+		if classify(body, loc[0]).Kind == "attribute" {
+			return loc[0], true
+		}
+	}
+	return -1, false
+}
+
+// Values longer than this are never fuzzy matched, each match costs
+// len(value)*len(body)
+const maxFuzzyLen = 256
+
+// Finds the substring of body with the smallest Levenshtein distance to
+// value, if it's at most maxDistance. Returns where it starts and the
+// distance.
+func matchFuzzy(body, value string, maxDistance int) (int, int, bool) {
+	// Sellers' algorithm, the edit distance matrix with a free start anywhere
+	// in body. Columns hold the distance of each value prefix to the best
+	// substring of body ending at the current byte, and where it starts.
+	m := len(value)
+	col, starts := make([]int, m+1), make([]int, m+1)
+	next, nextStarts := make([]int, m+1), make([]int, m+1)
+	for i := range col {
+		col[i] = i
+	}
+	best, bestStart := maxDistance+1, -1
+	for j := 0; j < len(body); j++ {
+		next[0], nextStarts[0] = 0, j+1
+		for i := 1; i <= m; i++ {
+			cost := 1
+			if value[i-1] == body[j] {
+				cost = 0
+			}
+			next[i], nextStarts[i] = col[i-1]+cost, starts[i-1]
+			if col[i]+1 < next[i] {
+				next[i], nextStarts[i] = col[i]+1, starts[i]
+			}
+			if next[i-1]+1 < next[i] {
+				next[i], nextStarts[i] = next[i-1]+1, nextStarts[i-1]
+			}
+		}
+		if next[m] < best {
+			best, bestStart = next[m], nextStarts[m]
+		}
+		col, next = next, col
+		starts, nextStarts = nextStarts, starts
+	}
+	if bestStart < 0 {
+		return -1, 0, false
+	}
+	return bestStart, best, true
+}
+
+// Looks for the value in body with up to maxDropped of its characters
+// missing, which is what sanitizers that strip characters leave behind.
+// Returns where the match starts and the dropped characters in order.
+func matchGaps(body, value string, maxDropped int) (int, string, bool) {
+	v := []byte(value)
+	// At least as many characters must survive as were dropped, otherwise
+	// short values match nearly anything
+	if limit := (len(v) - 1) / 2; limit < maxDropped {
+		maxDropped = limit
+	}
+	if maxDropped < 1 {
+		return -1, "", false
+	}
+	for i := 0; i < len(body); i++ {
+		// The first surviving character has to be among the first maxDropped+1
+		if strings.IndexByte(string(v[:maxDropped+1]), body[i]) < 0 {
+			continue
+		}
+		p := i
+		dropped := []byte{}
+		for _, c := range v {
+			if p < len(body) && body[p] == c {
+				p++
+			} else if dropped = append(dropped, c); maxDropped < len(dropped) {
+				break
+			}
+		}
+		if 0 < len(dropped) && len(dropped) <= maxDropped {
+			return i, string(dropped), true
+		}
+	}
+	return -1, "", false
+}
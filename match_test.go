@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestMatchGaps(t *testing.T) {
+	tests := []struct {
+		body, value string
+		maxDropped  int
+		offset      int
+		dropped     string
+		ok          bool
+	}{
+		{"x scriptalert(1)/script y", "<script>alert(1)</script>", 3, -1, "", false},
+		{"x scriptalert(1)/script y", "<script>alert(1)</script>", 4, 2, "<><>", true},
+		{"a foo bar", "foo<bar", 1, 2, "<", false},
+		{"a foobar", "foo<bar", 1, 2, "<", true},
+		{"a", "ab", 1, -1, "", false}, // Too short to drop any
+		{"nothing here", "value<x>", 2, -1, "", false},
+	}
+	for _, test := range tests {
+		offset, dropped, ok := matchGaps(test.body, test.value, test.maxDropped)
+		if ok != test.ok || (ok && (offset != test.offset || dropped != test.dropped)) {
+			t.Errorf("matchGaps(%q, %q, %d) = %d, %q, %t, want %d, %q, %t", test.body, test.value, test.maxDropped, offset, dropped, ok, test.offset, test.dropped, test.ok)
+		}
+	}
+}
+
+func TestMatchFuzzy(t *testing.T) {
+	tests := []struct {
+		body, value string
+		maxDistance int
+		offset      int
+		distance    int
+		ok          bool
+	}{
+		{"hello world", "world", 1, 6, 0, true},
+		{"hello wrld", "world", 1, 6, 1, true},
+		{"hello wzrld!", "world", 1, 6, 1, true},
+		{"hello wxyld", "world", 1, -1, 0, false},
+		{"hello wxyld", "world", 2, 6, 2, true},
+	}
+	for _, test := range tests {
+		offset, distance, ok := matchFuzzy(test.body, test.value, test.maxDistance)
+		if ok != test.ok || (ok && (offset != test.offset || distance != test.distance)) {
+			t.Errorf("matchFuzzy(%q, %q, %d) = %d, %d, %t, want %d, %d, %t", test.body, test.value, test.maxDistance, offset, distance, ok, test.offset, test.distance, test.ok)
+		}
+	}
+}
+
+func TestMatchAttrWhitespace(t *testing.T) {
+	tests := []struct {
+		body, value string
+		offset      int
+		ok          bool
+	}{
+		{`<div class=" foo  bar">`, "foo bar", 13, true},
+		{"<div class=\"foo\n\tbar\">", "foo bar", 12, true},
+		{`<p>foo  bar</p>`, "foo bar", -1, false},
+		{`<div class="foobar">`, "foo bar", -1, false},
+		{`<div class="x">`, " \t", -1, false},
+		// Binary values from decoding base64 params aren't valid patterns
+		{`<div class="x">`, "i\xb7\x1dy\xf8!\x8a9%", -1, false},
+		{"<div class=\"a \xf8\">", "a \xf8", -1, false},
+	}
+	for _, test := range tests {
+		offset, ok := matchAttrWhitespace(test.body, test.value)
+		if offset != test.offset || ok != test.ok {
+			t.Errorf("matchAttrWhitespace(%q, %q) = %d, %t, want %d, %t", test.body, test.value, offset, ok, test.offset, test.ok)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Reads a mitmproxy flow dump, e.g. from -w or --set save_stream_file, as a
+// HAR. Dumps are a stream of tnetstrings, one per flow.
+func readMitmproxy(r io.Reader) (*HAR, error) {
+	har := &HAR{}
+	br := bufio.NewReader(r)
+	for {
+		if _, err := br.Peek(1); err == io.EOF {
+			return har, nil
+		}
+		flow, err := readTNetstring(br)
+		if err != nil {
+			return nil, fmt.Errorf("reading flow %d: %w", len(har.Log.Entries), err)
+		}
+		flowMap, ok := flow.(map[string]interface{})
+		if !ok || flowMap["type"] != "http" {
+			continue
+		}
+		har.Log.Entries = append(har.Log.Entries, mitmproxyEntry(flowMap))
+	}
+}
+
+// Converts the fields of an http flow that scanning needs
+func mitmproxyEntry(flow map[string]interface{}) *Entry {
+	entry := &Entry{}
+	request, _ := flow["request"].(map[string]interface{})
+	scheme, host, path := toString(request["scheme"]), toString(request["host"]), toString(request["path"])
+	if port, _ := request["port"].(int64); port != 0 && !(scheme == "http" && port == 80) && !(scheme == "https" && port == 443) {
+		host += ":" + strconv.FormatInt(port, 10)
+	}
+	entry.Request.Method = toString(request["method"])
+	entry.Request.URL = scheme + "://" + host + path
+	entry.Request.Headers = mitmproxyHeaders(request["headers"])
+	if u, err := url.Parse(entry.Request.URL); err == nil {
+		entry.Request.QueryString = urlParams(u.RawQuery)
+	}
+	entry.Request.PostData.MimeType = header(entry.Request.Headers, "Content-Type")
+	entry.Request.PostData.Text = toString(request["content"])
+	if strings.HasPrefix(entry.Request.PostData.MimeType, "application/x-www-form-urlencoded") {
+		entry.Request.PostData.Params = urlParams(entry.Request.PostData.Text)
+	}
+
+	response, _ := flow["response"].(map[string]interface{})
+	status, _ := response["status_code"].(int64)
+	entry.Response.Status = int(status)
+	entry.Response.Headers = mitmproxyHeaders(response["headers"])
+	entry.Response.Content.MimeType = header(entry.Response.Headers, "Content-Type")
+	// Base64 like the HAR content scanning expects
+	entry.Response.Content.Encoding = "base64"
+	entry.Response.Content.Text = contentText(base64.StdEncoding.EncodeToString([]byte(toString(response["content"]))))
+	return entry
+}
+
+// Headers are a list of [name, value] pairs
+func mitmproxyHeaders(v interface{}) []Header {
+	headers := []Header{}
+	list, _ := v.([]interface{})
+	for _, pair := range list {
+		if pair, ok := pair.([]interface{}); ok && len(pair) == 2 {
+			headers = append(headers, Header{
+				Name:  toString(pair[0]),
+				Value: toString(pair[1]),
+			})
+		}
+	}
+	return headers
+}
+
+// Params of a query string or urlencoded form in order
+func urlParams(query string) []Param {
+	params := []Param{}
+	for _, param := range strings.Split(query, "&") {
+		if param == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(param, "=")
+		name, _ = url.QueryUnescape(name)
+		value, _ = url.QueryUnescape(value)
+		params = append(params, Param{
+			Name:  name,
+			Value: value,
+		})
+	}
+	return params
+}
+
+func toString(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+// Reads one tnetstring https://tnetstrings.info, bytes and strings both
+// become string
+func readTNetstring(r *bufio.Reader) (interface{}, error) {
+	lengthString, err := r.ReadString(':')
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(lengthString[:len(lengthString)-1])
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid tnetstring length %q", lengthString)
+	}
+	// Copied rather than allocated up front, so a huge length in a short
+	// input can't take all the memory
+	buf := bytes.Buffer{}
+	if _, err := io.CopyN(&buf, r, int64(length)); err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, err
+	}
+	data := buf.Bytes()
+	kind, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case ',', ';':
+		return string(data), nil
+	case '#':
+		return strconv.ParseInt(string(data), 10, 64)
+	case '^':
+		return strconv.ParseFloat(string(data), 64)
+	case '!':
+		return string(data) == "true", nil
+	case '~':
+		return nil, nil
+	case ']', '}':
+		items := []interface{}{}
+		inner := bufio.NewReader(strings.NewReader(string(data)))
+		for {
+			if _, err := inner.Peek(1); err == io.EOF {
+				break
+			}
+			item, err := readTNetstring(inner)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		if kind == ']' {
+			return items, nil
+		}
+		if len(items)%2 != 0 {
+			return nil, errors.New("tnetstring dict with a key but no value")
+		}
+		dict := map[string]interface{}{}
+		for i := 0; i < len(items); i += 2 {
+			dict[toString(items[i])] = items[i+1]
+		}
+		return dict, nil
+	}
+	return nil, fmt.Errorf("unknown tnetstring type %q", kind)
+}
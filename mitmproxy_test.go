@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadTNetstring(t *testing.T) {
+	tests := []struct {
+		in   string
+		want interface{}
+	}{
+		{"5:hello,", "hello"},
+		{"3:abc;", "abc"},
+		{"0:,", ""},
+		{"2:42#", int64(42)},
+		{"3:1.5^", 1.5},
+		{"4:true!", true},
+		{"5:false!", false},
+		{"0:~", nil},
+		{"8:1:a,1:b,]", []interface{}{"a", "b"}},
+		{"11:1:k,4:1:v,]}", map[string]interface{}{"k": []interface{}{"v"}}},
+	}
+	for _, test := range tests {
+		got, err := readTNetstring(bufio.NewReader(strings.NewReader(test.in)))
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("readTNetstring(%q) = %#v, %v, want %#v", test.in, got, err, test.want)
+		}
+	}
+	for _, in := range []string{"", "x:a,", "-1:,", "5:abc,", "3:abc?", "4:1:k,}", "99999999999999999:x", "9999999999:", "99999999999999999999:"} {
+		if got, err := readTNetstring(bufio.NewReader(strings.NewReader(in))); err == nil {
+			t.Errorf("readTNetstring(%q) = %#v, want an error", in, got)
+		}
+	}
+}
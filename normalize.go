@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Text normalized with a Unicode normalization form, and for each of its
+// bytes the offset in the original it came from
+type normalizedText struct {
+	text    string
+	offsets []int
+}
+
+var normalizeFlag = flag.String("normalize", "", "Also match values after Unicode normalization of both them and the response, space delimited forms of nfc, nfd, nfkc and nfkd")
+
+// Unicode normalizations by lowercase form, returning the normalized string
+// and for each of its bytes the offset in s it came from
+var normalizers = map[string]func(s string) (string, []int){
+	"nfc":  normalizer(norm.NFC),
+	"nfd":  normalizer(norm.NFD),
+	"nfkc": normalizer(norm.NFKC),
+	"nfkd": normalizer(norm.NFKD),
+}
+
+// Normalizes a string a segment at a time, so each byte of the result maps
+// to where its segment starts in the original
+func normalizer(form norm.Form) func(string) (string, []int) {
+	return func(s string) (string, []int) {
+		normalized := make([]byte, 0, len(s))
+		offsets := make([]int, 0, len(s)+1)
+		for i := 0; i < len(s); {
+			n := form.NextBoundaryInString(s[i:], true)
+			if n <= 0 {
+				n = len(s) - i
+			}
+			segment := form.String(s[i : i+n])
+			normalized = append(normalized, segment...)
+			for j := 0; j < len(segment); j++ {
+				offsets = append(offsets, i)
+			}
+			i += n
+		}
+		return string(normalized), append(offsets, len(s))
+	}
+}
+
+// Lowercase -normalize forms
+func normalizeForms() []string {
+	return strings.Fields(strings.ToLower(*normalizeFlag))
+}
+
+func setupNormalize() error {
+	for _, form := range normalizeForms() {
+		if _, ok := normalizers[form]; !ok {
+			return fmt.Errorf("unknown -normalize form %q", form)
+		}
+	}
+	return nil
+}
+
+// The body normalized with each -normalize form
+func (b *responseBody) normalizedBodies() map[string]normalizedText {
+	b.normalizeOnce.Do(func() {
+		b.normalized = map[string]normalizedText{}
+		for _, form := range normalizeForms() {
+			text, offsets := normalizers[form](b.text)
+			b.normalized[form] = normalizedText{text, offsets}
+		}
+	})
+	return b.normalized
+}
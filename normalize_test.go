@@ -0,0 +1,25 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizers(t *testing.T) {
+	tests := []struct {
+		form, s string
+		text    string
+		offsets []int
+	}{
+		{"nfc", "e\u0301x", "\u00e9x", []int{0, 0, 3, 4}},
+		{"nfd", "\u00e9x", "e\u0301x", []int{0, 0, 0, 2, 3}},
+		{"nfkc", "\uff21b", "Ab", []int{0, 3, 4}},
+		{"nfkd", "\ufb01", "fi", []int{0, 0, 3}},
+	}
+	for _, test := range tests {
+		text, offsets := normalizers[test.form](test.s)
+		if text != test.text || !reflect.DeepEqual(offsets, test.offsets) {
+			t.Errorf("%s(%q) = %q, %v, want %q, %v", test.form, test.s, text, offsets, test.text, test.offsets)
+		}
+	}
+}
@@ -0,0 +1,590 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// Picks the output writer from -template and a -format, sorting by -sort
+// first
+func resultWriter(format string) (func(io.Writer, []*Result) error, error) {
+	if *statsJSONFlag {
+		return writeStats, nil
+	}
+	if *countOnlyFlag {
+		return writeCount, nil
+	}
+	if *valuesOnlyFlag {
+		return writeValues, nil
+	}
+	write, err := formatWriter(format)
+	if err != nil || *sortFlag == "" {
+		return write, err
+	}
+	if _, ok := findingOrders[*sortFlag]; !ok && *sortFlag != "url" {
+		return nil, fmt.Errorf("unknown -sort %q", *sortFlag)
+	}
+	// Flat findings are sorted as one list instead, see flatten
+	flat := *flatFlag && (format == "json" || format == "ndjson")
+	return func(w io.Writer, results []*Result) error {
+		if !flat {
+			sortResults(results)
+		}
+		return write(w, results)
+	}, nil
+}
+
+func formatWriter(format string) (func(io.Writer, []*Result) error, error) {
+	if *templateFlag != "" {
+		tmpl, err := template.New("result").Parse(*templateFlag)
+		if err != nil {
+			return nil, err
+		}
+		return func(w io.Writer, results []*Result) error {
+			for _, result := range results {
+				if err := tmpl.Execute(w, result); err != nil {
+					return err
+				}
+				fmt.Fprintln(w)
+			}
+			return nil
+		}, nil
+	}
+	switch format {
+	case "json":
+		return writeJSON, nil
+	case "ndjson":
+		return writeNDJSON, nil
+	case "pairs":
+		return writePairs, nil
+	case "burp":
+		return writeBurp, nil
+	case "csv":
+		return writeCSV, nil
+	case "line":
+		return writeLines, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// Writes each reflected value once, escaping non printable runes so every
+// value is one line. Values -no-body-match forced aren't written.
+func writeValues(w io.Writer, results []*Result) error {
+	seen := map[string]bool{}
+	for _, result := range results {
+		for _, keyValue := range result.XSS {
+			if keyValue.Forced || seen[keyValue.Value] {
+				continue
+			}
+			seen[keyValue.Value] = true
+			if _, err := fmt.Fprintln(w, displayValue(keyValue.Value, -1)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Writes just the number of findings, logging how many there are of each
+// context
+func writeCount(w io.Writer, results []*Result) error {
+	count := 0
+	contexts := map[string]int{}
+	for _, result := range results {
+		count += len(result.XSS)
+		for _, keyValue := range result.XSS {
+			contexts[keyValue.Context]++
+		}
+	}
+	names := make([]string, 0, len(contexts))
+	for name := range contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	args := []any{}
+	for _, name := range names {
+		if name == "" {
+			args = append(args, "none", contexts[name])
+		} else {
+			args = append(args, name, contexts[name])
+		}
+	}
+	slog.Info("Findings by context", args...)
+	_, err := fmt.Fprintln(w, count)
+	return err
+}
+
+// Opens the -output-file for each -format, or stdout for a single format
+// without one, returning it and a write that renders results to every one.
+// With -merge-output json results are merged into the array already in the
+// file and ndjson is appended to it. There is no locking, runs merging into
+// the same file at the same time can lose results.
+func openOutput() (*os.File, func(io.Writer, []*Result) error, error) {
+	formats := strings.Split(*formatFlag, ",")
+	paths := []string{}
+	if *outputFileFlag != "" {
+		paths = strings.Split(*outputFileFlag, ",")
+	}
+	if 1 < len(formats) && (*templateFlag != "" || *countOnlyFlag || *valuesOnlyFlag || *statsJSONFlag || *byValueFlag || *canaryFlag != "") {
+		return nil, nil, errors.New("several -format don't work with -template, -count-only, -values-only, -stats-json, -by-value or -canary")
+	}
+	if 1 < len(formats) && len(paths) != len(formats) {
+		return nil, nil, fmt.Errorf("%d -format but %d -output-file, each format needs a file", len(formats), len(paths))
+	}
+	if len(paths) == 0 {
+		if *mergeOutputFlag {
+			return nil, nil, errors.New("-merge-output needs -output-file")
+		}
+		write, err := resultWriter(formats[0])
+		return os.Stdout, write, err
+	}
+	if len(paths) != len(formats) {
+		return nil, nil, fmt.Errorf("%d -output-file but %d -format", len(paths), len(formats))
+	}
+	files := make([]*os.File, len(formats))
+	writes := make([]func(io.Writer, []*Result) error, len(formats))
+	for i, format := range formats {
+		write, err := resultWriter(format)
+		if err != nil {
+			return nil, nil, err
+		}
+		if files[i], writes[i], err = openOutputFile(paths[i], format, write); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(files) == 1 {
+		return files[0], writes[0], nil
+	}
+	return nil, func(_ io.Writer, results []*Result) error {
+		for i, write := range writes {
+			if err := write(files[i], results); err != nil {
+				return fmt.Errorf("writing %s: %w", paths[i], err)
+			}
+		}
+		return nil
+	}, nil
+}
+
+// Opens one -output-file for format, adapting write for -merge-output
+func openOutputFile(path, format string, write func(io.Writer, []*Result) error) (*os.File, func(io.Writer, []*Result) error, error) {
+	if !*mergeOutputFlag {
+		f, err := os.Create(path)
+		return f, write, err
+	}
+	if *templateFlag != "" || *countOnlyFlag || *valuesOnlyFlag || *statsJSONFlag || *byValueFlag || *canaryFlag != "" || (format != "json" && format != "ndjson") {
+		return nil, nil, errors.New("-merge-output only works with -format json or ndjson")
+	}
+	if format == "ndjson" {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		return f, write, err
+	}
+	if *envelopeFlag || *flatFlag {
+		return nil, nil, errors.New("-merge-output doesn't work with -format json and -envelope or -flat")
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	return f, func(w io.Writer, results []*Result) error {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		existing := []*Result{}
+		if len(bytes.TrimSpace(data)) != 0 {
+			if err := json.Unmarshal(data, &existing); err != nil {
+				return fmt.Errorf("merging into -output-file: %w", err)
+			}
+		}
+		if err := f.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		return write(f, append(existing, results...))
+	}, err
+}
+
+// Whether finding a goes before b for each -sort other than url, most severe
+// and most matches first
+var findingOrders = map[string]func(a, b *KeyValue) bool{
+	"param": func(a, b *KeyValue) bool {
+		return a.Path() < b.Path()
+	},
+	"severity": func(a, b *KeyValue) bool {
+		return a.Severity > b.Severity
+	},
+	"matches": func(a, b *KeyValue) bool {
+		return len(a.Matches) > len(b.Matches)
+	},
+}
+
+// Orders results by -sort and -reverse. Sorting by a finding key orders the
+// findings of each result, then results by their first finding, results
+// without findings last.
+func sortResults(results []*Result) {
+	if less, ok := findingOrders[*sortFlag]; ok {
+		for _, result := range results {
+			sort.SliceStable(result.XSS, func(i, j int) bool {
+				return less(result.XSS[i], result.XSS[j])
+			})
+		}
+		sort.SliceStable(results, func(i, j int) bool {
+			a, b := results[i].XSS, results[j].XSS
+			return 0 < len(a) && (len(b) == 0 || less(a[0], b[0]))
+		})
+	} else if *sortFlag == "url" {
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].URL < results[j].URL
+		})
+	}
+	if *reverseFlag {
+		slices.Reverse(results)
+		for _, result := range results {
+			slices.Reverse(result.XSS)
+		}
+	}
+}
+
+// Orders flat findings by -sort and -reverse, across every result
+func sortFindings(findings []*FlatFinding) {
+	if less, ok := findingOrders[*sortFlag]; ok {
+		sort.SliceStable(findings, func(i, j int) bool {
+			return less(findings[i].KeyValue, findings[j].KeyValue)
+		})
+	} else if *sortFlag == "url" {
+		sort.SliceStable(findings, func(i, j int) bool {
+			return findings[i].URL < findings[j].URL
+		})
+	}
+	if *reverseFlag {
+		slices.Reverse(findings)
+	}
+}
+
+// Shortens values to -truncate-value runes and escapes non printable ones, for
+// display only since matching is done by now
+func truncateResult(result *Result) {
+	if *truncateValueFlag <= 0 {
+		return
+	}
+	for _, keyValue := range result.XSS {
+		keyValue.Value = displayValue(keyValue.Value, *truncateValueFlag)
+	}
+}
+
+// Keeps the -max-results-per-entry most severe findings, the first ones on
+// ties, in their original order
+func limitResult(result *Result) {
+	if *maxResultsPerEntryFlag <= 0 || len(result.XSS) <= *maxResultsPerEntryFlag {
+		return
+	}
+	ranked := slices.Clone(result.XSS)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Severity > ranked[j].Severity
+	})
+	kept := map[*KeyValue]bool{}
+	for _, keyValue := range ranked[:*maxResultsPerEntryFlag] {
+		kept[keyValue] = true
+	}
+	result.XSS = slices.DeleteFunc(result.XSS, func(keyValue *KeyValue) bool {
+		return !kept[keyValue]
+	})
+	result.Truncated = true
+}
+
+func displayValue(value string, n int) string {
+	display := strings.Builder{}
+	for i, r := range []rune(value) {
+		if i == n {
+			display.WriteString("…")
+			break
+		}
+		if unicode.IsPrint(r) {
+			display.WriteRune(r)
+		} else {
+			quoted := strconv.QuoteRune(r)
+			display.WriteString(quoted[1 : len(quoted)-1])
+		}
+	}
+	return display.String()
+}
+
+func writeJSON(w io.Writer, results []*Result) error {
+	if *flatFlag {
+		return encodeJSON(w, flatten(results))
+	}
+	return encodeJSON(w, results)
+}
+
+// One result per line, the CLI streams these as entries finish. With -flat
+// it's one finding per line.
+func writeNDJSON(w io.Writer, results []*Result) error {
+	enc := json.NewEncoder(w)
+	if *flatFlag {
+		for _, finding := range flatten(results) {
+			if err := enc.Encode(finding); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, result := range results {
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// A finding carrying the entry it's from, for -flat
+type FlatFinding struct {
+	File   string `json:"file,omitempty"`
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Source string `json:"source"` // First element of the key e.g. query
+	*KeyValue
+}
+
+// The findings of all results in one list sorted by -sort if set, skipped
+// results have none
+func flatten(results []*Result) []*FlatFinding {
+	findings := []*FlatFinding{}
+	for _, result := range results {
+		for _, keyValue := range result.XSS {
+			finding := &FlatFinding{
+				File:     result.File,
+				Method:   result.Method,
+				URL:      result.URL,
+				KeyValue: keyValue,
+			}
+			if 0 < len(keyValue.Key) {
+				finding.Source = keyValue.Key[0]
+			}
+			findings = append(findings, finding)
+		}
+	}
+	if *sortFlag != "" {
+		sortFindings(findings)
+	}
+	return findings
+}
+
+// Encodes the results of a json format, wrapped with report metadata if
+// -envelope is set
+func encodeJSON(w io.Writer, results interface{}) error {
+	if !*envelopeFlag {
+		return json.NewEncoder(w).Encode(results)
+	}
+	return json.NewEncoder(w).Encode(struct {
+		Meta    Meta        `json:"meta"`
+		Results interface{} `json:"results"`
+	}{
+		Meta:    reportMeta(),
+		Results: results,
+	})
+}
+
+type Meta struct {
+	Title   string            `json:"title,omitempty"`
+	Time    time.Time         `json:"time"`
+	Version string            `json:"version"`
+	Flags   map[string]string `json:"flags"` // Only those set on the command line
+}
+
+func reportMeta() Meta {
+	meta := Meta{
+		Title:   *reportTitleFlag,
+		Time:    time.Now().UTC(),
+		Version: "devel",
+		Flags:   map[string]string{},
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		meta.Version = info.Main.Version
+	}
+	flag.Visit(func(f *flag.Flag) {
+		meta.Flags[f.Name] = f.Value.String()
+		// Booleans can't leak anything
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); *redactArgsFlag && !(ok && b.IsBoolFlag()) {
+			meta.Flags[f.Name] = "REDACTED"
+		}
+	})
+	return meta
+}
+
+type Pair struct {
+	URL   string `json:"url"`
+	Param string `json:"param"`
+}
+
+// Distinct endpoint (url without query) and param (see paramName)
+// combinations that reflect in at least -min-reflections entries
+func writePairs(w io.Writer, results []*Result) error {
+	pairs := []Pair{}
+	reflections := map[Pair]int{}
+	for _, result := range results {
+		endpoint := result.URL
+		if u, err := url.Parse(result.URL); err == nil {
+			u.RawQuery, u.Fragment = "", ""
+			endpoint = u.String()
+		}
+		// Counted once per entry however many keys share the param name
+		seen := map[Pair]bool{}
+		for _, keyValue := range result.XSS {
+			if len(keyValue.Key) == 0 {
+				continue
+			}
+			pair := Pair{
+				URL:   endpoint,
+				Param: paramName(keyValue.Key),
+			}
+			if seen[pair] {
+				continue
+			}
+			seen[pair] = true
+			if reflections[pair] == 0 {
+				pairs = append(pairs, pair)
+			}
+			reflections[pair]++
+		}
+	}
+	kept := []Pair{}
+	for _, pair := range pairs {
+		if *minReflectionsFlag <= reflections[pair] {
+			kept = append(kept, pair)
+		}
+	}
+	return encodeJSON(w, kept)
+}
+
+// Last element of a key that isn't an array or repeated param index
+func paramName(key []string) string {
+	for i := len(key) - 1; 0 < i; i-- {
+		if _, err := strconv.Atoi(key[i]); err != nil {
+			return key[i]
+		}
+	}
+	return key[len(key)-1]
+}
+
+// A row per finding with its first match, for spreadsheets
+func writeCSV(w io.Writer, results []*Result) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"method", "url", "key", "value", "severity", "context", "escaping", "offset", "line", "column", "harPath"})
+	for _, result := range results {
+		for _, keyValue := range result.XSS {
+			offset, line, column := "", "", ""
+			if 0 < len(keyValue.Matches) {
+				match := keyValue.Matches[0]
+				offset, line, column = strconv.Itoa(match.Offset), strconv.Itoa(match.Line), strconv.Itoa(match.Column)
+			}
+			cw.Write([]string{
+				result.Method,
+				result.URL,
+				keyValue.Path(),
+				keyValue.Value,
+				strconv.Itoa(keyValue.Severity),
+				keyValue.Context,
+				keyValue.Escaping,
+				offset,
+				line,
+				column,
+				keyValue.HarPath,
+			})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Values longer than this are shortened in -format line unless
+// -truncate-value is set
+const lineValueLen = 60
+
+// One finding per line like POST https://example.com/ form.user.name => value
+// for grep and cut, values are shortened and escaped to stay on one line
+func writeLines(w io.Writer, results []*Result) error {
+	n := lineValueLen
+	if 0 < *truncateValueFlag {
+		n = *truncateValueFlag
+	}
+	for _, result := range results {
+		for _, keyValue := range result.XSS {
+			if _, err := fmt.Fprintf(w, "%s %s %s => %s\n", result.Method, result.URL, keyValue.Path(), displayValue(keyValue.Value, n)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Burp Suite issue export, only the elements Burp needs to import
+type burpIssues struct {
+	XMLName xml.Name    `xml:"issues"`
+	Issues  []burpIssue `xml:"issue"`
+}
+
+type burpIssue struct {
+	SerialNumber int    `xml:"serialNumber"`
+	Type         int    `xml:"type"`
+	Name         string `xml:"name"`
+	Host         string `xml:"host"`
+	Path         string `xml:"path"`
+	Location     string `xml:"location"`
+	Severity     string `xml:"severity"`
+	Confidence   string `xml:"confidence"`
+	IssueDetail  string `xml:"issueDetail"`
+}
+
+// Burp's "Input returned in response (reflected)" issue type
+const burpReflectedInputType = 0x00400a00
+
+// One issue per reflected parameter
+func writeBurp(w io.Writer, results []*Result) error {
+	issues := burpIssues{}
+	for _, result := range results {
+		host, path := result.URL, ""
+		if u, err := url.Parse(result.URL); err == nil {
+			host = (&url.URL{Scheme: u.Scheme, Host: u.Host}).String()
+			path = u.EscapedPath()
+		}
+		for _, keyValue := range result.XSS {
+			issues.Issues = append(issues.Issues, burpIssue{
+				SerialNumber: len(issues.Issues) + 1,
+				Type:         burpReflectedInputType,
+				Name:         "Input returned in response (reflected)",
+				Host:         host,
+				Path:         path,
+				Location:     fmt.Sprintf("%s [%s parameter]", path, keyValue.Path()),
+				Severity:     "Information",
+				Confidence:   "Certain",
+				IssueDetail:  fmt.Sprintf("The value of the %s parameter is copied into the response of %s %s: %s", keyValue.Path(), result.Method, result.URL, keyValue.Value),
+			})
+		}
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(issues); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestFlatSort(t *testing.T) {
+	defer func(flat bool, sort string) { *flatFlag, *sortFlag = flat, sort }(*flatFlag, *sortFlag)
+	*flatFlag, *sortFlag = true, "severity"
+	results := []*Result{
+		{URL: "a", XSS: []*KeyValue{{Key: []string{"query", "a"}, Severity: 7}, {Key: []string{"query", "b"}, Severity: 3}}},
+		{URL: "b", XSS: []*KeyValue{{Key: []string{"form", "c"}, Severity: 5}}},
+	}
+	for _, format := range []string{"json", "ndjson"} {
+		write, err := resultWriter(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := bytes.Buffer{}
+		if err := write(&out, results); err != nil {
+			t.Fatal(err)
+		}
+		severities := []int{}
+		dec := json.NewDecoder(&out)
+		for dec.More() {
+			findings := []*FlatFinding{}
+			if format == "ndjson" {
+				finding := &FlatFinding{}
+				if err := dec.Decode(finding); err != nil {
+					t.Fatal(err)
+				}
+				findings = append(findings, finding)
+			} else if err := dec.Decode(&findings); err != nil {
+				t.Fatal(err)
+			}
+			for _, finding := range findings {
+				severities = append(severities, finding.Severity)
+			}
+		}
+		if len(severities) != 3 || severities[0] != 7 || severities[1] != 5 || severities[2] != 3 {
+			t.Errorf("-format %s -flat -sort severity = %v, want [7 5 3]", format, severities)
+		}
+	}
+}
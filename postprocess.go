@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path"
+	"strings"
+)
+
+var minSeverityFlag = flag.Int("min-severity", 0, "Drop findings with a severity below this")
+var ignoreParamsFlag = flag.String("ignore-params", "", "Space delimited, case insensitive glob patterns of param names whose findings are dropped, matched against every element of the key e.g. '_ utm_*'")
+
+// Called with each finding once it's scored, it may change the finding and
+// returns false to drop it
+type findingFilter func(keyValue *KeyValue) bool
+
+// Filters scanEntry runs every finding through in order, set up from
+// -min-severity, -ignore-params and -param-value-regex
+var findingFilters []findingFilter
+
+func setupFindingFilters() error {
+	if *minSeverityFlag < 0 || 10 < *minSeverityFlag {
+		return fmt.Errorf("-min-severity %d not between 0 and 10", *minSeverityFlag)
+	}
+	if 0 < *minSeverityFlag {
+		findingFilters = append(findingFilters, func(keyValue *KeyValue) bool {
+			return *minSeverityFlag <= keyValue.Severity
+		})
+	}
+	if patterns := strings.Fields(strings.ToLower(*ignoreParamsFlag)); 0 < len(patterns) {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid -ignore-params pattern %q: %w", pattern, err)
+			}
+		}
+		findingFilters = append(findingFilters, func(keyValue *KeyValue) bool {
+			return !isSensitive(keyValue.Key, patterns)
+		})
+	}
+	return nil
+}
+
+// Whether keyValue passes every one of findingFilters
+func filterFinding(keyValue *KeyValue) bool {
+	for _, filter := range findingFilters {
+		if !filter(keyValue) {
+			return false
+		}
+	}
+	return true
+}
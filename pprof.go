@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+)
+
+var pprofFlag = flag.String("pprof", "", "Serve net/http/pprof on this address e.g. localhost:6060 while running, for go tool pprof")
+
+// Starts the -pprof server, on its own mux so -serve never exposes it
+func startPprof() error {
+	if *pprofFlag == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", *pprofFlag)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	slog.Info("Serving pprof", "addr", listener.Addr().String())
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			slog.Warn("Serving pprof", "err", err)
+		}
+	}()
+	return nil
+}
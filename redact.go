@@ -0,0 +1,125 @@
+package main
+
+import (
+	"net/url"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// Masks the values of params matching -redact-params for sharing reports,
+// the reflection itself is still reported
+func redactResult(result *Result) {
+	if !*redactFlag {
+		return
+	}
+	patterns := redactPatterns()
+	result.URL = redactURL(result.URL, patterns)
+
+	// Snippets of any finding can contain a sensitive value
+	replacements := []string{}
+	for _, keyValue := range result.XSS {
+		keyValue.RedirectedFrom = redactURL(keyValue.RedirectedFrom, patterns)
+		if isSensitive(keyValue.Key, patterns) {
+			for _, secret := range []string{keyValue.Value, keyValue.Escaped, keyValue.Evaluated} {
+				if secret != "" {
+					replacements = append(replacements, secret, mask(secret))
+				}
+			}
+			keyValue.Value = mask(keyValue.Value)
+			keyValue.Escaped = mask(keyValue.Escaped)
+			keyValue.Evaluated = mask(keyValue.Evaluated)
+		}
+	}
+	if result.Request != nil {
+		for _, secret := range result.Request.secrets {
+			replacements = append(replacements, secret, mask(secret))
+		}
+	}
+	if len(replacements) == 0 {
+		return
+	}
+	replacer := strings.NewReplacer(replacements...)
+	for _, keyValue := range result.XSS {
+		for i := range keyValue.Matches {
+			keyValue.Matches[i].Snippet = replacer.Replace(keyValue.Matches[i].Snippet)
+		}
+	}
+	if result.Request != nil {
+		result.Request.QueryString = redactQuery(result.Request.QueryString, patterns)
+		result.Request.PostData = replacer.Replace(result.Request.PostData)
+		// Form values that are escaped in the body don't match as is
+		if strings.HasPrefix(result.Request.MimeType, "application/x-www-form-urlencoded") {
+			result.Request.PostData = redactQuery(result.Request.PostData, patterns)
+		}
+	}
+}
+
+// Lowercase -redact-params globs
+func redactPatterns() []string {
+	return strings.Fields(strings.ToLower(*redactParamsFlag))
+}
+
+// Masks sensitive query params of a URL which would otherwise leak what
+// the reflection hides
+func redactURL(rawURL string, patterns []string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return rawURL
+	}
+	u.RawQuery = redactQuery(u.RawQuery, patterns)
+	return u.String()
+}
+
+// Masks the values of sensitive params of a raw query string
+func redactQuery(rawQuery string, patterns []string) string {
+	if rawQuery == "" {
+		return rawQuery
+	}
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		name, value, ok := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(name); ok && err == nil && isSensitive([]string{unescaped}, patterns) {
+			params[i] = name + "=" + maskQueryValue(value)
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// Masks an escaped query value, escaping the mask again so the query stays
+// valid e.g. hello%3Cb%3E is xxxxx%3Cx%3E
+func maskQueryValue(value string) string {
+	unescaped, err := url.QueryUnescape(value)
+	if err != nil {
+		// Already invalid, no worse for masking
+		return mask(value)
+	}
+	return url.QueryEscape(mask(unescaped))
+}
+
+// Whether any element of key matches one of the glob patterns, case
+// insensitive
+func isSensitive(key, patterns []string) bool {
+	for _, elem := range key {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, strings.ToLower(elem)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Keeps the shape of a value but not its content, letters become x and
+// digits 0 e.g. a JWT still looks like xxx.xxx.xxx
+func mask(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r):
+			return 'x'
+		case unicode.IsDigit(r):
+			return '0'
+		}
+		return r
+	}, value)
+}
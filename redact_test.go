@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRedactQuery(t *testing.T) {
+	patterns := []string{"q", "*token*"}
+	tests := []struct {
+		query, want string
+	}{
+		{"", ""},
+		{"q=hello%3Cb%3E", "q=xxxxx%3Cx%3E"},
+		{"q=a+b&page=2", "q=x+x&page=2"},
+		{"access_token=abc123&x=y", "access_token=xxx000&x=y"},
+		{"Q=secret", "Q=xxxxxx"},
+		{"q", "q"},
+		{"other=hello%3C", "other=hello%3C"},
+	}
+	for _, test := range tests {
+		if got := redactQuery(test.query, patterns); got != test.want {
+			t.Errorf("redactQuery(%q) = %q, want %q", test.query, got, test.want)
+		}
+	}
+}
+
+func TestRedactParamValueRegex(t *testing.T) {
+	defer func(redact, includeRequestBody bool, regex string, re *regexp.Regexp, filters []findingFilter) {
+		*redactFlag, *includeRequestBodyFlag, *paramValueRegexFlag = redact, includeRequestBody, regex
+		paramValueRegexp, findingFilters = re, filters
+	}(*redactFlag, *includeRequestBodyFlag, *paramValueRegexFlag, paramValueRegexp, findingFilters)
+	*redactFlag, *includeRequestBodyFlag, *paramValueRegexFlag = true, true, "<"
+	if err := setupParamValueRegex(); err != nil {
+		t.Fatal(err)
+	}
+	har := `{"log":{"entries":[{
+		"request":{"method":"POST","url":"https://example.com/login","postData":{"mimeType":"application/json","text":"{\"user\":\"<b>bob\",\"password\":\"hunter2\"}"}},
+		"response":{"status":200,"content":{"mimeType":"text/html","text":"<p><b>bob hunter2</p>"}}
+	}]}}`
+	results, err := collectHAR(context.Background(), strings.NewReader(har))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := bytes.Buffer{}
+	if err := writeJSON(&out, results); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "hunter2") || !strings.Contains(out.String(), `"key":["body","user"]`) {
+		t.Errorf("output with -redact and -param-value-regex = %s, want the password masked and only the user reported", out.String())
+	}
+}
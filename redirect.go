@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+)
+
+var followRedirectsFlag = flag.Bool("follow-redirects", false, "Also search the requests of redirects for values that reflect in the response they redirect to, matching Location to the URL of a later entry")
+
+// An earlier entry that redirected to an entry
+type redirectSource struct {
+	i     int
+	entry *Entry
+}
+
+// Links entries to the redirects that lead to them as they're read, by the
+// URL the redirect points to
+type redirectLinker struct {
+	pending map[string][]redirectSource
+}
+
+// Records which redirects led to the entry at index i, and where it
+// redirects if it does
+func (l *redirectLinker) link(i int, entry *Entry) {
+	if !*followRedirectsFlag {
+		return
+	}
+	if l.pending == nil {
+		l.pending = map[string][]redirectSource{}
+	}
+	requestURL := redirectKey(entry.Request.URL)
+	if sources, ok := l.pending[requestURL]; ok {
+		entry.redirectedFrom = sources
+		delete(l.pending, requestURL)
+	}
+	if entry.Response.Status < 300 || 400 <= entry.Response.Status {
+		return
+	}
+	location := header(entry.Response.Headers, "Location")
+	if location == "" {
+		location = entry.Response.RedirectURL
+	}
+	base, err := url.Parse(entry.Request.URL)
+	if err != nil || location == "" {
+		return
+	}
+	target, err := base.Parse(location)
+	if err != nil {
+		return
+	}
+	// The whole chain, so values reaching the end through several hops are
+	// still found
+	sources := append([]redirectSource{}, entry.redirectedFrom...)
+	l.pending[redirectKey(target.String())] = append(sources, redirectSource{i, entry})
+}
+
+// A URL without its fragment, which isn't sent so a request never has one
+func redirectKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.Fragment, u.RawFragment = "", ""
+	return u.String()
+}
+
+// The key values of searchEntry, then with -follow-redirects those of the
+// requests that redirected to the entry. Values the entry's own request has
+// are left out, most redirects just pass them on in the Location.
+func searchRedirects(ctx context.Context, entry *Entry) <-chan *KeyValue {
+	if len(entry.redirectedFrom) == 0 {
+		return searchEntry(ctx, entry)
+	}
+	keyValueChan := make(chan *KeyValue)
+	go func() {
+		defer close(keyValueChan)
+		own := map[string]bool{}
+		for keyValue := range searchEntry(ctx, entry) {
+			own[keyValue.Value] = true
+			if !send(ctx, keyValueChan, keyValue) {
+				return
+			}
+		}
+		for _, source := range entry.redirectedFrom {
+			for keyValue := range searchRequest(ctx, &source.entry.Request) {
+				if own[keyValue.Value] {
+					continue
+				}
+				keyValue.RedirectedFrom = source.entry.Request.URL
+				keyValue.HarPath = fmt.Sprintf("log.entries[%d].%s", source.i, keyValue.HarPath)
+				if !send(ctx, keyValueChan, keyValue) {
+					return
+				}
+			}
+		}
+	}()
+	return keyValueChan
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bounds the entries being scanned at once across every scan running, so
+// files scanned at the same time share -workers instead of each having as
+// many. Without setupWorkers each scan only bounds its own.
+var entrySlots chan struct{}
+
+func setupWorkers() {
+	entrySlots = make(chan struct{}, max(*workersFlag, 1))
+}
+
+// Scans entries with -workers goroutines, calling emit with each result or
+// nil if it isn't kept for output. Results are emitted in the order entries
+// arrive as soon as every entry before them is done, no matter how many
+// workers there are.
+func scan(ctx context.Context, entries <-chan indexedEntry, emit func(i int, result *Result)) {
+	type sequencedResult struct {
+		seq    int
+		i      int
+		result *Result
+	}
+	type sequencedEntry struct {
+		seq int
+		indexedEntry
+	}
+	entryChan := make(chan sequencedEntry)
+	resultChan := make(chan sequencedResult)
+	wg := sync.WaitGroup{}
+	for w := 0; w < max(*workersFlag, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range entryChan {
+				if entrySlots != nil {
+					entrySlots <- struct{}{}
+				}
+				result := scanIndex(ctx, e.entry, e.i)
+				if entrySlots != nil {
+					<-entrySlots
+				}
+				resultChan <- sequencedResult{e.seq, e.i, result}
+			}
+		}()
+	}
+	go func() {
+		seq := 0
+		for e := range entries {
+			entryChan <- sequencedEntry{seq, e}
+			seq++
+		}
+		close(entryChan)
+		wg.Wait()
+		close(resultChan)
+	}()
+
+	// Buffers results that finish before ones that arrived earlier
+	pending := map[int]sequencedResult{}
+	next := 0
+	for sr := range resultChan {
+		pending[sr.seq] = sr
+		for sr, ok := pending[next]; ok; sr, ok = pending[next] {
+			delete(pending, next)
+			result := sr.result
+			// Stats count skipped entries
+			if result.Skipped != "" && !*includeSkippedFlag && !*statsJSONFlag {
+				result = nil
+			} else {
+				limitResult(result)
+				redactResult(result)
+				truncateResult(result)
+			}
+			emit(sr.i, result)
+			next++
+		}
+	}
+}
+
+// Scans the entry at index i with the -timeout-per-entry limit and logs how it went
+func scanIndex(ctx context.Context, entry *Entry, i int) *Result {
+	entryStart := time.Now()
+	cancel := context.CancelFunc(func() {})
+	if 0 < *timeoutPerEntryFlag {
+		ctx, cancel = context.WithTimeout(ctx, *timeoutPerEntryFlag)
+	}
+	defer cancel()
+	result, err := scanEntry(ctx, entry)
+	if err != nil {
+		slog.Warn("Skipping entry", "index", i, "url", entry.Request.URL, "err", err)
+		return skipped(entry, err.Error())
+	}
+	for _, keyValue := range result.XSS {
+		// Already has the index of the entry it's from
+		if keyValue.RedirectedFrom != "" {
+			continue
+		}
+		keyValue.HarPath = fmt.Sprintf("log.entries[%d].%s", i, keyValue.HarPath)
+	}
+	if result.Skipped != "" {
+		slog.Log(ctx, explainLevel(), "Filtered entry", "index", i, "url", entry.Request.URL, "reason", result.Skipped)
+	} else {
+		slog.Debug("Scanned entry", "index", i, "url", entry.Request.URL, "findings", len(result.XSS), "duration", time.Since(entryStart))
+	}
+	return result
+}
+
+// Searches the request of an entry for values reflected in its response,
+// entries that are filtered out have a Skipped result
+func scanEntry(ctx context.Context, entry *Entry) (*Result, error) {
+	if reason, err := filterEntry(entry); err != nil {
+		return nil, err
+	} else if reason != "" {
+		return skipped(entry, reason), nil
+	}
+	mimeType := responseMimeType(&entry.Response)
+	bodyText, err := decodeResponse(&entry.Response)
+	if err != nil {
+		return nil, err
+	}
+	body := newResponseBody(bodyText)
+
+	isHTML := strings.Contains(mimeType, "html")
+	isSVG := mimeType == "image/svg+xml"
+	isXML := *xmlFlag && isXMLMimeType(mimeType)
+	body.isXML = isXML
+	csp := header(entry.Response.Headers, "Content-Security-Policy")
+	strictCSP := isStrictCSP(csp)
+	var decodedBody *responseBody
+	if decoded := decodedBodyText(bodyText); decoded != "" {
+		decodedBody = newResponseBody(decoded)
+		decodedBody.isXML = isXML
+	}
+	keyValues := []*KeyValue{}
+	for keyValue := range searchRedirects(ctx, entry) {
+		// The body the value matched in, the rest of the steps work on it
+		matched := body
+		switch {
+		case matchBody(body, keyValue):
+		case decodedBody != nil && matchBody(decodedBody, keyValue):
+			matched, keyValue.DecodedBody = decodedBody, true
+		case *noBodyMatchFlag:
+			// Matched at the start of the body anyway, so the context and
+			// scoring steps run on every value
+			keyValue.Forced = true
+			keyValue.Matches = []Match{newMatch(body.text, 0, 0)}
+		default:
+			explain(entry, keyValue, explainMiss(keyValue))
+			continue
+		}
+		if isHTML {
+			describeContext(matched.text, keyValue.Matches[0].Offset, keyValue)
+		} else if isSVG {
+			describeSVGContext(matched.text, keyValue.Matches[0].Offset, keyValue)
+		}
+		if isXML && keyValue.XMLPath == "" {
+			keyValue.XMLPath = xmlPath(matched, keyValue.Matches[0].Offset)
+		}
+		// Forced values reflect in no form
+		if !keyValue.Forced {
+			keyValue.Forms = reflectionForms(matched, keyValue.Value)
+			keyValue.FullReflection = isFullReflection(matched.text, keyValue.Value)
+		}
+		keyValue.Severity = severity(keyValue, strictCSP)
+		keyValue.Confidence = confidence(keyValue)
+		if !filterFinding(keyValue) {
+			explain(entry, keyValue, explainMatch(keyValue)+", but dropped by a finding filter")
+			continue
+		}
+		explain(entry, keyValue, explainMatch(keyValue))
+		keyValues = append(keyValues, keyValue)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var request *RawRequest
+	if *includeRequestBodyFlag {
+		request = rawRequest(ctx, &entry.Request)
+	}
+	return &Result{
+		Method:   entry.Request.Method,
+		URL:      entry.Request.URL,
+		MimeType: mimeType,
+		Status:   entry.Response.Status,
+		BodySize: len(bodyText),
+		XSS:      keyValues,
+
+		RequestBodySize:  knownSize(entry.Request.BodySize, len(entry.Request.PostData.Text)),
+		ResponseBodySize: knownSize(entry.Response.BodySize, len(bodyText)),
+		Request:          request,
+		CSP:              csp,
+
+		FromCache:   isFromCache(&entry.Response),
+		BodyMissing: isBodyMissing(&entry.Response),
+	}, nil
+}
+
+// Matches the value against the body, plain and then as XML text and an
+// evaluated template, filling in where it reflects
+func matchBody(body *responseBody, keyValue *KeyValue) bool {
+	if offset, ok := match(body, keyValue); ok {
+		keyValue.Matches = collectMatches(body.text, offset, keyValue)
+	} else if offset, path, ok := matchXML(body, keyValue.Value); ok {
+		keyValue.Escaping, keyValue.XMLPath = "xml", path
+		keyValue.Matches = []Match{newMatch(body.text, offset, len(keyValue.Value))}
+	} else if offset, ok := matchSSTI(body.text, keyValue); ok {
+		keyValue.Matches = []Match{newMatch(body.text, offset, len(keyValue.Evaluated))}
+	} else {
+		return false
+	}
+	return true
+}
+
+// The size a HAR recorded, or else computed when it's -1 for unknown or 0
+// which some tools record for every entry
+func knownSize(recorded, computed int) int {
+	if 0 < recorded {
+		return recorded
+	}
+	return computed
+}
+
+// Share of the body a value has to make up to be a full reflection
+const fullReflectionRatio = 0.9
+
+// Whether the value is (nearly) the whole body, like echo and JSONP endpoints
+// that return their input verbatim
+func isFullReflection(body, value string) bool {
+	body = strings.TrimSpace(body)
+	return 0 < len(body) && fullReflectionRatio <= float64(len(value))/float64(len(body))
+}
+
+// The response body as the browser saw it
+func decodeResponse(response *Response) (string, error) {
+	respBody, err := base64.StdEncoding.DecodeString(string(response.Content.Text))
+	if err != nil && response.Content.Encoding == "" {
+		// The HAR spec has text literal unless encoding says otherwise, but
+		// plenty of exporters base64 encode without saying so
+		slog.Debug("Body isn't base64, using it as is", "err", err)
+		respBody = []byte(response.Content.Text)
+	} else if err != nil {
+		return "", err
+	}
+	if strings.Contains(strings.ToLower(header(response.Headers, "Transfer-Encoding")), "chunked") {
+		respBody = dechunk(respBody)
+	}
+	if strings.EqualFold(strings.TrimSpace(header(response.Headers, "Content-Transfer-Encoding")), "quoted-printable") {
+		respBody = decodeQuotedPrintable(respBody)
+	}
+	if hint := compressionHint(response, respBody); hint != "" {
+		respBody = decodeContent(hint, respBody)
+	}
+	respBody = decodeContent(header(response.Headers, "Content-Encoding"), respBody)
+	respBody = gunzipBase64(respBody)
+	if *stripBOMFlag {
+		respBody = bytes.ReplaceAll(respBody, []byte("\uFEFF"), nil)
+	}
+	bodyText := string(respBody)
+	if *unescapeBodyFlag {
+		bodyText = html.UnescapeString(bodyText)
+	}
+	return bodyText, nil
+}
+
+// With -decode-base64-body, what a body that is entirely base64 decodes to,
+// matched on top of the body itself. Empty when it doesn't decode.
+func decodedBodyText(bodyText string) string {
+	if !*decodeBase64BodyFlag {
+		return ""
+	}
+	if decoded := string(decodeBase64Body([]byte(bodyText))); decoded != bodyText {
+		return decoded
+	}
+	return ""
+}
+
+// The parts of a request needed to send it again
+type RawRequest struct {
+	QueryString string `json:"queryString,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"` // Of the body
+	PostData    string `json:"postData,omitempty"`
+
+	// Values of the request's -redact-params, to mask wherever they are in
+	// PostData
+	secrets []string
+}
+
+func rawRequest(ctx context.Context, request *Request) *RawRequest {
+	raw := &RawRequest{
+		MimeType: request.PostData.MimeType,
+		PostData: request.PostData.Text,
+	}
+	if u, err := url.Parse(request.URL); err == nil {
+		raw.QueryString = u.RawQuery
+	}
+	if *redactFlag {
+		patterns := redactPatterns()
+		for keyValue := range searchRequest(ctx, request) {
+			if keyValue.Value != "" && isSensitive(keyValue.Key, patterns) {
+				raw.secrets = append(raw.secrets, keyValue.Value)
+			}
+		}
+	}
+	return raw
+}
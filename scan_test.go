@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"testing"
+)
+
+// Scans a .har fixture to json like the CLI writes it
+func scanFixture(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	results, err := collectHAR(context.Background(), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := bytes.Buffer{}
+	if err := writeJSON(&out, results); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestWorkersDeterministic(t *testing.T) {
+	defer func(workers int) { *workersFlag = workers }(*workersFlag)
+	want := ""
+	for _, workers := range []int{1, 2, 8} {
+		*workersFlag = workers
+		got := scanFixture(t, "testdata/entries.har")
+		if workers == 1 {
+			want = got
+			if !bytes.Contains([]byte(got), []byte(`"harPath":"log.entries[23].`)) {
+				t.Fatalf("expected findings for every entry, got %s", got)
+			}
+			continue
+		}
+		if got != want {
+			t.Errorf("output with -workers %d differs from -workers 1", workers)
+		}
+	}
+}
+
+func TestScanFilesSharedSlots(t *testing.T) {
+	defer func(workers int, slots chan struct{}) { *workersFlag, entrySlots = workers, slots }(*workersFlag, entrySlots)
+	*workersFlag = 4
+	setupWorkers()
+	want := scanFixture(t, "testdata/entries.har")
+	results, ok := scanFiles([]string{"testdata/entries.har", "testdata/missing.har", "testdata/entries.har"})
+	if ok {
+		t.Error("scanFiles with a missing file = ok, want it to fail")
+	}
+	half := len(results) / 2
+	for _, result := range results {
+		if result.File != "testdata/entries.har" {
+			t.Fatalf("result of file %q, want testdata/entries.har", result.File)
+		}
+		// Scanned alone it has no file
+		result.File = ""
+	}
+	for i, results := range [][]*Result{results[:half], results[half:]} {
+		got := bytes.Buffer{}
+		if err := writeJSON(&got, results); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != want {
+			t.Errorf("results of file %d differ from scanning it alone", i)
+		}
+	}
+}
+
+func TestScanXML(t *testing.T) {
+	defer func(xml bool) { *xmlFlag = xml }(*xmlFlag)
+	*xmlFlag = true
+	entry := &Entry{}
+	entry.Request.QueryString = []Param{{Name: "name", Value: "alice<x>"}}
+	entry.Response.Content.MimeType = "text/xml"
+	entry.Response.Content.Text = "<a><b>alice&lt;x&gt;</b></a>"
+	result, err := scanEntry(context.Background(), entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Skipped != "" || len(result.XSS) != 1 || result.XSS[0].XMLPath != "a.b" {
+		t.Errorf("scanEntry of a text/xml entry with -xml = %+v, want a finding at a.b", result)
+	}
+}
+
+func TestScanJSON(t *testing.T) {
+	entry := &Entry{}
+	entry.Request.QueryString = []Param{{Name: "q", Value: `a"b<i>`}}
+	entry.Response.Content.MimeType = "application/json"
+	entry.Response.Content.Text = `{"q":"a\"b\u003ci\u003e"}`
+	result, err := scanEntry(context.Background(), entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Skipped != "" || len(result.XSS) != 1 || result.XSS[0].Escaping != "json" {
+		t.Errorf("scanEntry of an application/json entry = %+v, want a json escaped finding", result)
+	}
+}
+
+func TestScanDecodeBase64Body(t *testing.T) {
+	defer func(decode bool) { *decodeBase64BodyFlag = decode }(*decodeBase64BodyFlag)
+	*decodeBase64BodyFlag = true
+	blob := base64.StdEncoding.EncodeToString([]byte("<p>alice</p>"))
+	entry := &Entry{}
+	entry.Request.QueryString = []Param{{Name: "name", Value: "alice"}, {Name: "state", Value: blob}}
+	entry.Response.Content.MimeType = "text/html"
+	entry.Response.Content.Encoding = "base64"
+	entry.Response.Content.Text = contentText(base64.StdEncoding.EncodeToString([]byte(blob)))
+	result, err := scanEntry(context.Background(), entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]*KeyValue{}
+	for _, keyValue := range result.XSS {
+		values[keyValue.Value] = keyValue
+	}
+	if name := values["alice"]; name == nil || !name.DecodedBody || name.Matches[0].Offset != 3 {
+		t.Errorf("value in the decoded body = %+v, want a match at 3 of the decoded body", name)
+	}
+	if state := values[blob]; state == nil || state.DecodedBody {
+		t.Errorf("value in the base64 body = %+v, want a match in the body itself", state)
+	}
+}
+
+func TestScanHARCanceled(t *testing.T) {
+	f, err := os.Open("testdata/entries.har")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	scanned := 0
+	err = scanHAR(ctx, f, 0, func(i int, result *Result) {
+		scanned++
+	})
+	if err != context.Canceled || scanned != 0 {
+		t.Errorf("scanHAR with a done context = %v after %d entries, want %v after none", err, scanned, context.Canceled)
+	}
+}
+
+func TestScanNoBodyMatch(t *testing.T) {
+	defer func(noBodyMatch bool) { *noBodyMatchFlag = noBodyMatch }(*noBodyMatchFlag)
+	*noBodyMatchFlag = true
+	entry := &Entry{}
+	entry.Request.QueryString = []Param{{Name: "a", Value: "reflected"}, {Name: "b", Value: "absent"}}
+	entry.Response.Content.MimeType = "text/html"
+	entry.Response.Content.Text = "<p>reflected</p>"
+	result, err := scanEntry(context.Background(), entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]*KeyValue{}
+	for _, keyValue := range result.XSS {
+		values[keyValue.Value] = keyValue
+	}
+	reflected, absent := values["reflected"], values["absent"]
+	if reflected == nil || absent == nil {
+		t.Fatalf("scanEntry with -no-body-match = %+v, want every value", result.XSS)
+	}
+	if reflected.Forced || reflected.Confidence != "exact" {
+		t.Errorf("reflected value = %+v, want an exact match", reflected)
+	}
+	if !absent.Forced || absent.Confidence != "none" || absent.Context == "" || absent.Severity == 0 || len(absent.Forms) != 0 {
+		t.Errorf("absent value = %+v, want it forced with a context and severity", absent)
+	}
+}
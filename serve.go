@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// Serves a single endpoint that scans a POSTed .har file and responds with
+// the results, formatted like the CLI would print them
+func serve(addr string, write func(io.Writer, []*Result) error) error {
+	slog.Info("Serving", "addr", addr)
+	return http.ListenAndServe(addr, serveHandler(write))
+}
+
+func serveHandler(write func(io.Writer, []*Result) error) http.Handler {
+	contentType := "application/json"
+	if *templateFlag != "" || *countOnlyFlag || *valuesOnlyFlag || *formatFlag == "line" {
+		contentType = "text/plain; charset=utf-8"
+	} else if *formatFlag == "burp" {
+		contentType = "application/xml"
+	} else if *formatFlag == "csv" {
+		contentType = "text/csv; charset=utf-8"
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "POST a .har file", http.StatusMethodNotAllowed)
+			return
+		}
+		results, err := collectHAR(r.Context(), http.MaxBytesReader(w, r.Body, *serveMaxBytesFlag))
+		if tooLarge := (*http.MaxBytesError)(nil); errors.As(err, &tooLarge) {
+			slog.Warn("Scanning HAR", "remote", r.RemoteAddr, "err", err)
+			http.Error(w, fmt.Sprintf("larger than -serve-max-bytes %d", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		} else if err != nil {
+			slog.Warn("Scanning HAR", "remote", r.RemoteAddr, "err", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Buffered so a write error can still be a 500
+		buf := bytes.Buffer{}
+		if err := write(&buf, results); err != nil {
+			slog.Error("Writing results", "remote", r.RemoteAddr, "err", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		buf.WriteTo(w)
+	}
+	return http.HandlerFunc(handler)
+}
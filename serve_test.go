@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestServeMaxBytes(t *testing.T) {
+	defer func(maxBytes int64) { *serveMaxBytesFlag = maxBytes }(*serveMaxBytesFlag)
+	har, err := os.ReadFile("testdata/entries.har")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := serveHandler(writeJSON)
+	for _, test := range []struct {
+		maxBytes int64
+		status   int
+	}{
+		{int64(len(har)), http.StatusOK},
+		{int64(len(har)) - 1, http.StatusRequestEntityTooLarge},
+	} {
+		*serveMaxBytesFlag = test.maxBytes
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(har)))
+		if rec.Code != test.status {
+			t.Errorf("POST of %d bytes with -serve-max-bytes %d = %d, want %d", len(har), test.maxBytes, rec.Code, test.status)
+		}
+	}
+}
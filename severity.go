@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+)
+
+// Base severity by Context, see the rubric in README.md
+var contextSeverity = map[string]int{
+	"srcdoc":        9,
+	"script":        8,
+	"data-uri":      8,
+	"tag":           7,
+	"svg":           7,
+	"url-attribute": 7,
+	"attribute":     6,
+	"text":          5,
+	"style":         5,
+	"json-script":   4,
+	"comment":       3,
+	"":              2, // Not HTML
+}
+
+// Scores how exploitable a reflection looks from 0 to 10, in a response with
+// a strict Content-Security-Policy or not
+func severity(keyValue *KeyValue, strictCSP bool) int {
+	// Evaluated server side, likely code execution
+	if keyValue.Finding == "ssti" {
+		return 10
+	}
+	score := contextSeverity[keyValue.Context]
+	if keyValue.Context == "url-attribute" && keyValue.URLStart {
+		score += 2
+	}
+	if keyValue.URLScheme != "" {
+		score++
+	}
+	// Normalization can turn lookalikes into markup characters
+	reflected := keyValue.Value
+	if keyValue.Normalization != "" {
+		reflected = keyValue.Escaped
+	}
+	switch keyValue.Escaping {
+	case "":
+		// Markup characters that survive unescaped make breaking out likely
+		if strings.ContainsAny(reflected, htmlSpecialChars) {
+			score += 2
+		}
+	case "html":
+		score -= 4
+	case "json":
+		// Can't break out of the JSON string, but may still be markup to a
+		// page that inserts it
+		score -= 2
+	}
+	if keyValue.Dropped != "" || keyValue.Distance != 0 || keyValue.Fuzzy {
+		score -= 2
+	}
+	if keyValue.FullReflection {
+		score = max(score, 9)
+	}
+	if strictCSP {
+		score -= 3
+	}
+	return min(max(score, 0), 10)
+}
+
+// How reliable the match of a finding is: exact for the value as is, encoded
+// for an escaped or encoded form of it, partial with characters dropped or
+// whitespace changed and heuristic for the rest, which can be coincidence.
+// None for values -no-body-match forced.
+func confidence(keyValue *KeyValue) string {
+	switch {
+	case keyValue.Forced:
+		return "none"
+	case keyValue.Escaping != "", keyValue.Normalization != "":
+		return "encoded"
+	case keyValue.Dropped != "", keyValue.Whitespace:
+		return "partial"
+	case keyValue.Finding != "", keyValue.Distance != 0, keyValue.Fuzzy, *matchRegexFlag != "":
+		return "heuristic"
+	}
+	return "exact"
+}
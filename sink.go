@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var sinkURLFlag = flag.String("sink-url", "", "Also POST each finding as JSON to this URL as it's found, like a -flat finding. Failed posts are logged, or fail the scan with -strict")
+
+// Posts in flight to -sink-url, scanning waits for a free one
+const sinkWorkers = 4
+
+// Attempts per finding, backing off a little longer after each
+const sinkAttempts = 3
+
+// The -sink-url findings are posted to, nil without one
+var findingSink *sink
+
+// Posts findings to a URL with sinkWorkers goroutines
+type sink struct {
+	url      string
+	client   *http.Client
+	findings chan *FlatFinding
+	wg       sync.WaitGroup
+
+	mu     sync.Mutex
+	failed error // First failed post
+}
+
+func setupSink() error {
+	if *sinkURLFlag == "" {
+		return nil
+	}
+	if u, err := url.Parse(*sinkURLFlag); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("-sink-url %q isn't an http or https URL", *sinkURLFlag)
+	}
+	findingSink = &sink{
+		url:      *sinkURLFlag,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		findings: make(chan *FlatFinding),
+	}
+	for w := 0; w < sinkWorkers; w++ {
+		findingSink.wg.Add(1)
+		go func() {
+			defer findingSink.wg.Done()
+			for finding := range findingSink.findings {
+				findingSink.post(finding)
+			}
+		}()
+	}
+	return nil
+}
+
+// Waits for findings to be posted to the -sink-url if there is one, see
+// sink.close
+func closeSink() error {
+	if findingSink == nil {
+		return nil
+	}
+	return findingSink.close()
+}
+
+// Queues the findings of a result, blocking while every worker is busy so
+// a slow sink slows the scan instead of buffering without bound
+func (s *sink) send(result *Result) {
+	for _, finding := range flatten([]*Result{result}) {
+		s.findings <- finding
+	}
+}
+
+// Waits for the queued findings to be posted, with -strict the error is the
+// first post that failed
+func (s *sink) close() error {
+	close(s.findings)
+	s.wg.Wait()
+	if *strictFlag {
+		return s.failed
+	}
+	return nil
+}
+
+func (s *sink) post(finding *FlatFinding) {
+	data, err := json.Marshal(finding)
+	if err != nil {
+		s.fail(finding, err)
+		return
+	}
+	for attempt := 1; ; attempt++ {
+		err = s.postOnce(data)
+		if err == nil || attempt == sinkAttempts {
+			break
+		}
+		time.Sleep(time.Duration(attempt) * 500 * time.Millisecond)
+	}
+	if err != nil {
+		s.fail(finding, err)
+	}
+}
+
+func (s *sink) postOnce(data []byte) error {
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || 300 <= resp.StatusCode {
+		return errors.New(resp.Status)
+	}
+	return nil
+}
+
+func (s *sink) fail(finding *FlatFinding, err error) {
+	slog.Warn("Posting finding to -sink-url", "url", finding.URL, "key", finding.Path(), "err", err)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failed == nil {
+		s.failed = fmt.Errorf("posting finding to -sink-url: %w", err)
+	}
+}
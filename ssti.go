@@ -0,0 +1,58 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Arithmetic in the delimiters of common template engines e.g. {{7*7}} or
+// ${7*191}, as pre-injected by the tester
+var sstiRegexp = regexp.MustCompile(`(\{\{|\$\{|#\{|<%=|\[\[)\s*(\d{1,9})\s*([*+-])\s*(\d{1,9})\s*(\}\}|\}|%>|\]\])`)
+
+// The value with each template expression in it replaced by its result, or
+// false if it has none
+func evaluateSSTI(value string) (string, bool) {
+	if !sstiRegexp.MatchString(value) {
+		return "", false
+	}
+	return sstiRegexp.ReplaceAllStringFunc(value, func(expr string) string {
+		groups := sstiRegexp.FindStringSubmatch(expr)
+		a, _ := strconv.Atoi(groups[2])
+		b, _ := strconv.Atoi(groups[4])
+		switch groups[3] {
+		case "*":
+			return strconv.Itoa(a * b)
+		case "+":
+			return strconv.Itoa(a + b)
+		default:
+			return strconv.Itoa(a - b)
+		}
+	}), true
+}
+
+// Reports whether keyValue's template expressions reflect evaluated in body
+// and at which offset with -ssti, marking keyValue as an ssti finding
+func matchSSTI(body string, keyValue *KeyValue) (int, bool) {
+	if !*sstiFlag {
+		return -1, false
+	}
+	evaluated, ok := evaluateSSTI(keyValue.Value)
+	if !ok {
+		return -1, false
+	}
+	// 49 inside 1492 isn't the result of 7*7
+	isDigit := func(i int) bool { return 0 <= i && i < len(body) && '0' <= body[i] && body[i] <= '9' }
+	for offset := 0; ; {
+		i := strings.Index(body[offset:], evaluated)
+		if i == -1 {
+			return -1, false
+		}
+		start, end := offset+i, offset+i+len(evaluated)
+		if !isDigit(start-1) && !isDigit(end) {
+			keyValue.Finding, keyValue.Evaluated = "ssti", evaluated
+			return start, true
+		}
+		offset = start + 1
+	}
+}
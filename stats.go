@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"strconv"
+	"time"
+)
+
+var statsJSONFlag = flag.Bool("stats-json", false, "Instead of findings output one json object of scan metrics, for dashboards tracking a target over time")
+
+// When the process started, for Stats.Duration
+var startTime = time.Now()
+
+type Stats struct {
+	Entries  int `json:"entries"` // Scanned and skipped
+	Scanned  int `json:"scanned"`
+	Skipped  int `json:"skipped"`
+	Findings int `json:"findings"`
+
+	// Findings by the first key element e.g. query, by severity, and by
+	// escaping with raw for none
+	BySource   map[string]int `json:"bySource"`
+	BySeverity map[string]int `json:"bySeverity"`
+	ByEncoding map[string]int `json:"byEncoding"`
+
+	Duration float64 `json:"durationSeconds"` // Since the process started
+}
+
+// Writes the Stats of results, which include skipped entries with
+// -stats-json
+func writeStats(w io.Writer, results []*Result) error {
+	stats := Stats{
+		Entries:    len(results),
+		BySource:   map[string]int{},
+		BySeverity: map[string]int{},
+		ByEncoding: map[string]int{},
+	}
+	for _, result := range results {
+		if result.Skipped != "" {
+			stats.Skipped++
+			continue
+		}
+		stats.Scanned++
+		for _, keyValue := range result.XSS {
+			stats.Findings++
+			if 0 < len(keyValue.Key) {
+				stats.BySource[keyValue.Key[0]]++
+			}
+			stats.BySeverity[strconv.Itoa(keyValue.Severity)]++
+			encoding := keyValue.Escaping
+			if encoding == "" {
+				encoding = "raw"
+			}
+			stats.ByEncoding[encoding]++
+		}
+	}
+	stats.Duration = time.Since(startTime).Seconds()
+	return encodeJSON(w, stats)
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"log/slog"
+)
+
+// HAR versions whose quirks are known
+var harVersions = map[string]bool{
+	"1.1": true,
+	"1.2": true,
+}
+
+// Logs diagnostics about the capture, never fatal
+func validate(har *HAR) {
+	creator := har.Log.Creator.Name
+	if creator == "" {
+		creator = "unknown"
+	}
+	slog.Info("HAR creator", "name", creator, "version", har.Log.Creator.Version)
+	switch version := har.Log.Version; {
+	case version == "":
+		slog.Warn("HAR log.version is missing")
+	case !harVersions[version]:
+		slog.Warn("Unexpected HAR log.version, expected 1.1 or 1.2", "version", version)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/xml"
+	"flag"
+	"strings"
+)
+
+var xmlFlag = flag.Bool("xml", false, "Scan and parse XML responses e.g. SOAP, on top of -content-types, matching values in unescaped text and attributes and reporting the element path they reflect in")
+
+// Mime types -xml parses
+func isXMLMimeType(mimeType string) bool {
+	return mimeType == "application/xml" || mimeType == "text/xml" || strings.HasSuffix(mimeType, "+xml")
+}
+
+// Calls fn with the dotted path of local element names e.g.
+// Envelope.Body.GetUserResponse.name, or with a final @attr for attributes,
+// where each text node or attribute value starts in body and its unescaped
+// text, until fn returns false or the XML ends or is invalid
+func walkXML(body string, fn func(path string, start int, text string) bool) {
+	decoder := xml.NewDecoder(strings.NewReader(body))
+	decoder.Strict = false
+	path := []string{}
+	for {
+		start := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err != nil {
+			return
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			path = append(path, token.Name.Local)
+			for _, attr := range token.Attr {
+				if !fn(strings.Join(append(path, "@"+attr.Name.Local), "."), start, attr.Value) {
+					return
+				}
+			}
+		case xml.EndElement:
+			if 0 < len(path) {
+				path = path[:len(path)-1]
+			}
+		case xml.CharData:
+			if !fn(strings.Join(path, "."), start, string(token)) {
+				return
+			}
+		}
+	}
+}
+
+// A text node or attribute value of an XML body
+type xmlNode struct {
+	path  string
+	start int
+	text  string
+}
+
+// The text nodes and attributes of the body when it's XML to parse
+func (b *responseBody) xmlNodes() []xmlNode {
+	b.xmlNodesOnce.Do(func() {
+		if !b.isXML {
+			return
+		}
+		walkXML(b.text, func(path string, start int, text string) bool {
+			b.nodes = append(b.nodes, xmlNode{path, start, text})
+			return true
+		})
+	})
+	return b.nodes
+}
+
+// The path of the text node or attribute the reflection at offset is in
+func xmlPath(b *responseBody, offset int) string {
+	found := ""
+	for _, node := range b.xmlNodes() {
+		if offset < node.start {
+			break
+		}
+		found = node.path
+	}
+	return found
+}
+
+// Finds value in the unescaped text nodes and attributes of an XML body,
+// returning where the node starts and its path
+func matchXML(b *responseBody, value string) (int, string, bool) {
+	for _, node := range b.xmlNodes() {
+		if value != "" && strings.Contains(node.text, value) {
+			return node.start, node.path, true
+		}
+	}
+	return -1, "", false
+}